@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"fmt"
 	"log"
@@ -9,11 +10,13 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-contrib/cors"
+	"github.com/lib/pq"
 	"service-monitor/internal/config"
 	"service-monitor/internal/services"
 	"service-monitor/pkg/database"
@@ -25,6 +28,11 @@ var (
 	db            *sql.DB
 	userService   *services.UserService
 	serviceService *services.ServiceService
+	scheduler     *services.Scheduler
+	heartbeatService *services.HeartbeatService
+	healthCheckService *services.HealthCheckService
+	maintenanceService *services.MaintenanceService
+	apiToken      string
 )
 
 func main() {
@@ -64,9 +72,56 @@ func main() {
 	notifyService := notifications.NewTwilioService(&cfg.Twilio)
 	userService = services.NewUserService(db)
 	serviceService = services.NewServiceService(db)
-	_ = notifyService // keep for future use
+	alertService := services.NewAlertService(db, notifyService)
+	healthCheckService = services.NewHealthCheckService(db, alertService)
+	heartbeatService = services.NewHeartbeatService(db, healthCheckService)
+	maintenanceService = services.NewMaintenanceService(db)
+	services.RegisterChecker(models.ServiceTypeHeartbeat, heartbeatService)
+	if cfg.Checks.Scripts.Enabled {
+		scriptChecker, err := services.NewScriptChecker(services.SandboxFromConfig(&cfg.Checks.Scripts))
+		if err != nil {
+			log.Fatalf("Failed to enable custom scripts: %v", err)
+		}
+		services.RegisterChecker(models.ServiceTypeCustom, scriptChecker)
+	}
 	log.Printf("Services initialized")
 
+	// Start health check scheduler
+	scheduler = services.NewScheduler(db, serviceService, healthCheckService)
+	if cfg.Checks.Mode == "queue" {
+		scheduler.UseQueue(services.NewCheckQueue(redis, cfg.Checks.LeaseDuration()))
+		log.Printf("Health checks will be run by queue workers")
+	}
+
+	rawRetention, hourlyRetention := retention(&cfg.Retention)
+	rollupService := services.NewRollupService(db, rawRetention, hourlyRetention)
+
+	// Only the elected replica schedules checks, sends escalations and rolls
+	// up health checks
+	elector := services.NewLeaderElector(redis, cfg.Leader.LeaseDuration())
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		elector.Run(leaderCtx, func(ctx context.Context, token int64) {
+			if err := scheduler.Start(ctx); err != nil {
+				log.Printf("Failed to start scheduler: %v", err)
+				return
+			}
+			log.Printf("Health check scheduler started")
+			defer scheduler.Stop()
+
+			rollupsDone := make(chan struct{})
+			go func() {
+				defer close(rollupsDone)
+				rollupService.Run(ctx)
+			}()
+			defer func() { <-rollupsDone }()
+
+			alertService.RunEscalations(ctx, token)
+		})
+	}()
+
 	// Initialize router
 	router := gin.Default()
 
@@ -81,24 +136,41 @@ func main() {
 
 	log.Printf("Router initialized")
 
+	apiToken = cfg.Server.APIToken
+	if apiToken == "" {
+		log.Printf("server.api_token is not set: changes through the API are unauthenticated and service tests are disabled")
+	}
+
 	// API routes
 	api := router.Group("/api")
 	{
 		// Service routes
 		services := api.Group("/services")
 		{
-			services.POST("", createService)
+			services.POST("", requireAPIToken, createService)
+			services.POST("/test", requireAPIToken, testService)
 			services.GET("", listServices)
 			services.GET("/:id", getService)
-			services.PUT("/:id", updateService)
-			services.DELETE("/:id", deleteService)
+			services.PUT("/:id", requireAPIToken, updateService)
+			services.DELETE("/:id", requireAPIToken, deleteService)
 		}
 
+		// Dependency graph route
+		api.GET("/dependencies", getDependencyGraph)
+
 		// Health check routes
 		health := api.Group("/health")
 		{
 			health.GET("/:id", checkServiceHealth)
 			health.GET("/:id/history", getHealthHistory)
+			health.GET("/:id/uptime", getUptime)
+		}
+
+		// Heartbeat ping routes, called by monitored jobs
+		ping := api.Group("/ping")
+		{
+			ping.Any("/:token", pingHeartbeat)
+			ping.Any("/:token/:event", pingHeartbeat)
 		}
 
 		// Alert routes
@@ -106,34 +178,47 @@ func main() {
 		{
 			alerts.GET("", listAlerts)
 			alerts.GET("/:id", getAlert)
-			alerts.POST("/:id/resolve", resolveAlert)
-			alerts.POST("/:id/verify", verifyAlert)
+			alerts.POST("/:id/resolve", requireAPIToken, resolveAlert)
+			alerts.POST("/:id/verify", requireAPIToken, verifyAlert)
 		}
 
 		// User routes
 		users := api.Group("/users")
 		{
-			users.POST("", createUser)
+			users.POST("", requireAPIToken, createUser)
 			users.GET("", listUsers)
 			users.GET("/:id", getUser)
-			users.PUT("/:id", updateUser)
-			users.DELETE("/:id", deleteUser)
+			users.PUT("/:id", requireAPIToken, updateUser)
+			users.DELETE("/:id", requireAPIToken, deleteUser)
 		}
 
 		// Escalation chain routes
 		escalation := api.Group("/escalation")
 		{
-			escalation.POST("", createEscalationChain)
+			escalation.POST("", requireAPIToken, createEscalationChain)
 			escalation.GET("/:service_id", getEscalationChain)
-			escalation.PUT("/:id", updateEscalationChain)
-			escalation.DELETE("/:id", deleteEscalationChain)
+			escalation.PUT("/:id", requireAPIToken, updateEscalationChain)
+			escalation.DELETE("/:id", requireAPIToken, deleteEscalationChain)
 		}
 
 		// Settings routes
 		settings := api.Group("/settings")
 		{
 			settings.GET("", getSettings)
-			settings.PUT("", updateSettings)
+			settings.PUT("", requireAPIToken, updateSettings)
+		}
+
+		// Schedule routes
+		api.POST("/schedules/preview", previewSchedule)
+
+		// Maintenance window routes
+		maintenance := api.Group("/maintenance")
+		{
+			maintenance.POST("", requireAPIToken, createMaintenanceWindow)
+			maintenance.GET("", listMaintenanceWindows)
+			maintenance.GET("/:id", getMaintenanceWindow)
+			maintenance.PUT("/:id", requireAPIToken, updateMaintenanceWindow)
+			maintenance.DELETE("/:id", requireAPIToken, deleteMaintenanceWindow)
 		}
 	}
 
@@ -155,6 +240,12 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	// Stop scheduling new checks and hand over leadership before the server
+	// goes away
+	stopLeader()
+	<-leaderDone
+	log.Println("Health check scheduler stopped")
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -166,6 +257,25 @@ func main() {
 	log.Println("Server exiting")
 }
 
+// retention returns how long raw checks and hourly rollups are kept, with 0
+// keeping hourly rollups forever.
+func retention(cfg *config.RetentionConfig) (time.Duration, time.Duration) {
+	raw := 30 * 24 * time.Hour
+	if cfg.RawDays > 0 {
+		raw = time.Duration(cfg.RawDays) * 24 * time.Hour
+	}
+
+	hourly := 365 * 24 * time.Hour
+	switch {
+	case cfg.HourlyDays < 0:
+		hourly = 0
+	case cfg.HourlyDays > 0:
+		hourly = time.Duration(cfg.HourlyDays) * 24 * time.Hour
+	}
+
+	return raw, hourly
+}
+
 // --- Handler Stubs ---
 
 func createService(c *gin.Context) {
@@ -175,18 +285,37 @@ func createService(c *gin.Context) {
 		return
 	}
 
+	if err := services.ValidateService(&service); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid service config: %v", err)})
+		return
+	}
+
+	if err := serviceService.ValidateDependencies(c.Request.Context(), 0, service.DependsOn); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid dependencies: %v", err)})
+		return
+	}
+
 	newService, err := serviceService.CreateService(c.Request.Context(), &service)
 	if err != nil {
 		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to create service: %v", err)})
 		return
 	}
 
+	// The ping token is only returned to whoever creates or updates the
+	// service
+	redactService(newService)
+	if err := assignPingToken(c.Request.Context(), newService); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to create service: %v", err)})
+		return
+	}
+
+	scheduler.Refresh()
 	c.JSON(201, newService)
 }
 
 func listServices(c *gin.Context) {
 	query := `
-		SELECT id, name, type, url, config, created_at, updated_at
+		SELECT id, name, type, url, config, tags, created_at, updated_at
 		FROM services
 		ORDER BY created_at DESC
 	`
@@ -207,6 +336,7 @@ func listServices(c *gin.Context) {
 			&service.Type,
 			&service.URL,
 			&service.Config,
+			pq.Array(&service.Tags),
 			&service.CreatedAt,
 			&service.UpdatedAt,
 		)
@@ -214,6 +344,7 @@ func listServices(c *gin.Context) {
 			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to scan service: %v", err)})
 			return
 		}
+		redactService(&service)
 		services = append(services, service)
 	}
 
@@ -243,6 +374,7 @@ func getService(c *gin.Context) {
 		return
 	}
 
+	redactService(service)
 	c.JSON(200, service)
 }
 
@@ -260,6 +392,16 @@ func updateService(c *gin.Context) {
 		return
 	}
 
+	if err := services.ValidateService(&service); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid service config: %v", err)})
+		return
+	}
+
+	if err := serviceService.ValidateDependencies(c.Request.Context(), serviceID, service.DependsOn); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid dependencies: %v", err)})
+		return
+	}
+
 	service.ID = serviceID
 	updatedService, err := serviceService.UpdateService(c.Request.Context(), &service)
 	if err != nil {
@@ -271,6 +413,13 @@ func updateService(c *gin.Context) {
 		return
 	}
 
+	redactService(updatedService)
+	if err := assignPingToken(c.Request.Context(), updatedService); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to update service: %v", err)})
+		return
+	}
+
+	scheduler.Refresh()
 	c.JSON(200, updatedService)
 }
 
@@ -292,21 +441,448 @@ func deleteService(c *gin.Context) {
 		return
 	}
 
+	scheduler.Refresh()
+	c.Status(204)
+}
+
+func getDependencyGraph(c *gin.Context) {
+	graph, err := serviceService.GetDependencyGraph(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get dependency graph: %v", err)})
+		return
+	}
+
+	c.JSON(200, graph)
+}
+
+// requireAPIToken rejects requests without the configured API token as a
+// bearer token. Without a configured token every request is let through.
+func requireAPIToken(c *gin.Context) {
+	if apiToken == "" {
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) != 1 {
+		c.AbortWithStatusJSON(401, gin.H{"error": "Invalid or missing API token"})
+		return
+	}
+}
+
+// redactService blanks the database password and masks any password in the
+// URL before a service is returned. Updates that leave them that way keep
+// the stored passwords as long as the URL still points at the same target.
+// The heartbeat ping token is blanked too, as anyone holding it can report
+// the job's runs; create and update responses add it back.
+func redactService(service *models.Service) {
+	service.Config.Password = ""
+	service.URL = services.RedactURL(service.URL)
+	service.PingToken = ""
+}
+
+// assignPingToken gives heartbeat services their ping token.
+func assignPingToken(ctx context.Context, service *models.Service) error {
+	if !services.IsHeartbeat(service) {
+		return nil
+	}
+
+	token, err := heartbeatService.EnsureToken(ctx, service.ID)
+	if err != nil {
+		return err
+	}
+	service.PingToken = token
+
+	return nil
+}
+
+// pingHeartbeat is called by monitored jobs. The optional event is "start",
+// "fail" or the job's exit code, where non-zero counts as a failure.
+func pingHeartbeat(c *gin.Context) {
+	event := services.HeartbeatSuccess
+	var exitCode *int
+
+	switch param := c.Param("event"); param {
+	case "":
+	case services.HeartbeatStart, services.HeartbeatFail:
+		event = param
+	default:
+		code, err := strconv.Atoi(param)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid ping event"})
+			return
+		}
+		exitCode = &code
+		if code != 0 {
+			event = services.HeartbeatFail
+		}
+	}
+
+	_, err := heartbeatService.Ping(c.Request.Context(), c.Param("token"), event, exitCode)
+	if err != nil {
+		if err.Error() == "heartbeat not found" {
+			c.JSON(404, gin.H{"error": "Heartbeat not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to record ping: %v", err)})
+		return
+	}
+
+	c.JSON(200, gin.H{"status": "ok"})
+}
+
+// previewSchedule returns the next run times of a cron expression so it can
+// be checked before it is saved.
+func previewSchedule(c *gin.Context) {
+	var req struct {
+		Schedule string `json:"schedule" binding:"required"`
+		Timezone string `json:"timezone"`
+		Count    int    `json:"count"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 5
+	}
+
+	runs, err := services.NextRuns(req.Schedule, req.Timezone, time.Now(), req.Count)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid schedule: %v", err)})
+		return
+	}
+
+	c.JSON(200, gin.H{"runs": runs})
+}
+
+func createMaintenanceWindow(c *gin.Context) {
+	var window models.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+
+	if err := maintenanceService.ValidateWindow(&window); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid maintenance window: %v", err)})
+		return
+	}
+
+	if err := maintenanceService.CreateWindow(c.Request.Context(), &window); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to create maintenance window: %v", err)})
+		return
+	}
+
+	c.JSON(201, window)
+}
+
+func listMaintenanceWindows(c *gin.Context) {
+	windows, err := maintenanceService.ListWindows(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to fetch maintenance windows: %v", err)})
+		return
+	}
+
+	c.JSON(200, windows)
+}
+
+func getMaintenanceWindow(c *gin.Context) {
+	windowID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid maintenance window ID"})
+		return
+	}
+
+	window, err := maintenanceService.GetWindow(c.Request.Context(), windowID)
+	if err != nil {
+		if err.Error() == "maintenance window not found" {
+			c.JSON(404, gin.H{"error": "Maintenance window not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get maintenance window: %v", err)})
+		return
+	}
+
+	c.JSON(200, window)
+}
+
+func updateMaintenanceWindow(c *gin.Context) {
+	windowID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid maintenance window ID"})
+		return
+	}
+
+	var window models.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+
+	if err := maintenanceService.ValidateWindow(&window); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid maintenance window: %v", err)})
+		return
+	}
+
+	window.ID = windowID
+	if err := maintenanceService.UpdateWindow(c.Request.Context(), &window); err != nil {
+		if err.Error() == "maintenance window not found" {
+			c.JSON(404, gin.H{"error": "Maintenance window not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to update maintenance window: %v", err)})
+		return
+	}
+
+	c.JSON(200, window)
+}
+
+func deleteMaintenanceWindow(c *gin.Context) {
+	windowID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid maintenance window ID"})
+		return
+	}
+
+	if err := maintenanceService.DeleteWindow(c.Request.Context(), windowID); err != nil {
+		if err.Error() == "maintenance window not found" {
+			c.JSON(404, gin.H{"error": "Maintenance window not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to delete maintenance window: %v", err)})
+		return
+	}
+
 	c.Status(204)
 }
 
+// testService checks a service definition without saving it or its result.
+func testService(c *gin.Context) {
+	// A test probes any address the caller chooses, so it is never open
+	if apiToken == "" {
+		c.JSON(403, gin.H{"error": "Testing services requires server.api_token to be set"})
+		return
+	}
+
+	var service models.Service
+	if err := c.ShouldBindJSON(&service); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+
+	if err := services.ValidateService(&service); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid service config: %v", err)})
+		return
+	}
+
+	if services.IsHeartbeat(&service) {
+		c.JSON(400, gin.H{"error": "Heartbeat services are checked by their pings and cannot be tested"})
+		return
+	}
+	// Scripts only ever run once saved as part of a service
+	if strings.EqualFold(string(service.Type), string(models.ServiceTypeCustom)) {
+		c.JSON(400, gin.H{"error": "Custom script services cannot be tested"})
+		return
+	}
+
+	check, err := healthCheckService.TestService(c.Request.Context(), &service)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to test service: %v", err)})
+		return
+	}
+
+	c.JSON(200, check)
+}
+
 func checkServiceHealth(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented"}) // TODO: implement
+	id := c.Param("id")
+	serviceID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	service, err := serviceService.GetService(c.Request.Context(), serviceID)
+	if err != nil {
+		if err.Error() == "service not found" {
+			c.JSON(404, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get service: %v", err)})
+		return
+	}
+
+	check, err := healthCheckService.CheckService(c.Request.Context(), service)
+	if err != nil {
+		// The result is recorded even if updating state or alerts fails
+		if check == nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to check service: %v", err)})
+			return
+		}
+		log.Printf("Failed to update state for service %d: %v", serviceID, err)
+	}
+
+	c.JSON(200, check)
 }
 
+// getHealthHistory returns a service's checks in a time range, newest first
+// and paginated with a cursor, or with bucket set, aggregated points oldest
+// first. The range defaults to the last 24 hours.
 func getHealthHistory(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented"}) // TODO: implement
+	id := c.Param("id")
+	serviceID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	from, to, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := services.HistoryQuery{From: from, To: to, Limit: 100, Cursor: c.Query("cursor")}
+	if value := c.Query("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			switch status {
+			case "up", "degraded", "down", "unknown":
+				query.Statuses = append(query.Statuses, status)
+			default:
+				c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid status %q", status)})
+				return
+			}
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil || query.Limit <= 0 || query.Limit > 1000 {
+			c.JSON(400, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	maintenance, err := maintenanceService.Periods(c.Request.Context(), serviceID, from, to)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get health history: %v", err)})
+		return
+	}
+
+	if value := c.Query("bucket"); value != "" {
+		bucket, err := services.ParseBucket(value)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if query.Cursor != "" {
+			c.JSON(400, gin.H{"error": "cursor cannot be used with bucket"})
+			return
+		}
+		if to.Sub(from)/bucket > services.MaxHistoryBuckets {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("Range spans more than %d buckets", services.MaxHistoryBuckets)})
+			return
+		}
+
+		// The bucket may be widened to whole hours or days where rollups are read
+		points, bucket, err := healthCheckService.GetHealthBuckets(c.Request.Context(), serviceID, query, bucket)
+		if err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get health history: %v", err)})
+			return
+		}
+
+		c.JSON(200, gin.H{"bucket": services.FormatBucket(bucket), "points": points, "maintenance": maintenance})
+		return
+	}
+
+	checks, next, err := healthCheckService.GetHealthHistory(c.Request.Context(), serviceID, query)
+	if err != nil {
+		if err.Error() == "invalid cursor" {
+			c.JSON(400, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get health history: %v", err)})
+		return
+	}
+
+	c.JSON(200, gin.H{"checks": checks, "next_cursor": next, "maintenance": maintenance})
+}
+
+// getUptime reports a service's availability over a time range, the last 30
+// days by default, and its error budget over the SLO period ending with the
+// range. Maintenance is excluded unless exclude_maintenance=false.
+func getUptime(c *gin.Context) {
+	id := c.Param("id")
+	serviceID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	from, to, err := parseTimeRange(c, 30*24*time.Hour)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	excludeMaintenance := true
+	if value := c.Query("exclude_maintenance"); value != "" {
+		excludeMaintenance, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid exclude_maintenance"})
+			return
+		}
+	}
+
+	service, err := serviceService.GetService(c.Request.Context(), serviceID)
+	if err != nil {
+		if err.Error() == "service not found" {
+			c.JSON(404, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get service: %v", err)})
+		return
+	}
+
+	uptime, err := healthCheckService.GetUptime(c.Request.Context(), service, from, to, excludeMaintenance)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to get uptime: %v", err)})
+		return
+	}
+
+	c.JSON(200, uptime)
+}
+
+// parseTimeRange reads the from and to query parameters as RFC 3339 times.
+// to defaults to now and from to span before to.
+func parseTimeRange(c *gin.Context, span time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid to: %v", err)
+		}
+		to = t
+	}
+
+	from := to.Add(-span)
+	if value := c.Query("from"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid from: %v", err)
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+
+	return from, to, nil
 }
 
 func listAlerts(c *gin.Context) {
 	query := `
 		SELECT a.id, a.service_id, s.name as service_name, a.status, 
-		       a.started_at, a.resolved_at, a.verification_status,
+		       a.severity, a.message, a.started_at, a.resolved_at, a.verification_status,
 		       a.created_at, a.updated_at
 		FROM alerts a
 		JOIN services s ON s.id = a.service_id
@@ -334,6 +910,8 @@ func listAlerts(c *gin.Context) {
 			&alert.ServiceID,
 			&alert.ServiceName,
 			&alert.Status,
+			&alert.Severity,
+			&alert.Message,
 			&alert.StartedAt,
 			&alert.ResolvedAt,
 			&alert.VerificationStatus,
@@ -586,5 +1164,7 @@ func updateSettings(c *gin.Context) {
 		return
 	}
 
+	// The global check interval may have changed
+	scheduler.Refresh()
 	c.JSON(200, settings)
 } 
\ No newline at end of file
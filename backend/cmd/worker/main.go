@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"service-monitor/internal/config"
+	"service-monitor/internal/models"
+	"service-monitor/internal/services"
+	"service-monitor/pkg/database"
+	"service-monitor/pkg/notifications"
+)
+
+// The worker runs health checks queued by the API's scheduler when
+// checks.mode is "queue". Any number of workers can run against the same
+// database and Redis.
+func main() {
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	log.Printf("Configuration loaded successfully")
+
+	// Initialize database
+	log.Printf("Connecting to database at %s:%d", cfg.Database.Host, cfg.Database.Port)
+	db, err := database.NewPostgresDB(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	log.Printf("Database connection established")
+
+	// Initialize Redis
+	log.Printf("Connecting to Redis at %s:%d", cfg.Redis.Host, cfg.Redis.Port)
+	redis, err := database.NewRedisClient(&cfg.Redis)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redis.Close()
+	log.Printf("Redis connection established")
+
+	// Initialize services
+	notifyService := notifications.NewTwilioService(&cfg.Twilio)
+	serviceService := services.NewServiceService(db)
+	alertService := services.NewAlertService(db, notifyService)
+	healthCheckService := services.NewHealthCheckService(db, alertService)
+	services.RegisterChecker(models.ServiceTypeHeartbeat, services.NewHeartbeatService(db, healthCheckService))
+	if cfg.Checks.Scripts.Enabled {
+		scriptChecker, err := services.NewScriptChecker(services.SandboxFromConfig(&cfg.Checks.Scripts))
+		if err != nil {
+			log.Fatalf("Failed to enable custom scripts: %v", err)
+		}
+		services.RegisterChecker(models.ServiceTypeCustom, scriptChecker)
+	}
+
+	queue := services.NewCheckQueue(redis, cfg.Checks.LeaseDuration())
+	worker := services.NewWorker(queue, serviceService, healthCheckService, cfg.Checks.Workers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		worker.Run(ctx)
+	}()
+	log.Printf("Worker started with %d concurrent checks", max(cfg.Checks.Workers, 1))
+
+	// Wait for interrupt signal to gracefully shutdown the worker
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down worker...")
+
+	cancel()
+	<-done
+	log.Println("Worker exiting")
+}
@@ -3,20 +3,25 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
-	Twilio   TwilioConfig   `yaml:"twilio"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Redis     RedisConfig     `yaml:"redis"`
+	Twilio    TwilioConfig    `yaml:"twilio"`
+	Checks    ChecksConfig    `yaml:"checks"`
+	Leader    LeaderConfig    `yaml:"leader"`
+	Retention RetentionConfig `yaml:"retention"`
 }
 
 type ServerConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	APIToken string `yaml:"api_token"` // bearer token required by every request that changes data or tests services
 }
 
 type DatabaseConfig struct {
@@ -41,6 +46,61 @@ type TwilioConfig struct {
 	FromNumber  string `yaml:"from_number"`
 }
 
+// ChecksConfig controls where health checks run. In "local" mode (the
+// default) the API process runs them itself; in "queue" mode it only
+// schedules them and worker processes (cmd/worker) run them.
+type ChecksConfig struct {
+	Mode         string        `yaml:"mode"`
+	Workers      int           `yaml:"workers"`       // concurrent checks per worker process
+	LeaseSeconds int           `yaml:"lease_seconds"` // how long a worker holds a check before it is retried
+	Scripts      ScriptsConfig `yaml:"scripts"`
+}
+
+// LeaseDuration is how long a worker holds a check, 60s unless configured.
+func (c *ChecksConfig) LeaseDuration() time.Duration {
+	if c.LeaseSeconds > 0 {
+		return time.Duration(c.LeaseSeconds) * time.Second
+	}
+	return 60 * time.Second
+}
+
+// ScriptsConfig allows custom script checks, which are off by default since
+// they run arbitrary commands on the checking host. When enabled, scripts run
+// as User, chrooted into Root, with the given limits; zero limits use the
+// defaults. The server must run as root to switch to User.
+type ScriptsConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	User         string `yaml:"user"`          // dedicated unprivileged account
+	Root         string `yaml:"root"`          // must contain /bin/sh and /tmp
+	CPUSeconds   int    `yaml:"cpu_seconds"`   // default 10
+	MemoryMB     int    `yaml:"memory_mb"`     // address space, default 256
+	MaxProcesses int    `yaml:"max_processes"` // across all of User's processes, default 32
+	MaxFileMB    int    `yaml:"max_file_mb"`   // default 16
+}
+
+// LeaderConfig tunes leader election between API replicas. Only the leader
+// schedules checks and sends escalations; if it stops renewing its lease,
+// another replica takes over within about one lease.
+type LeaderConfig struct {
+	LeaseSeconds int `yaml:"lease_seconds"`
+}
+
+// LeaseDuration is how long a leader's lease lasts, 15s unless configured.
+func (c *LeaderConfig) LeaseDuration() time.Duration {
+	if c.LeaseSeconds > 0 {
+		return time.Duration(c.LeaseSeconds) * time.Second
+	}
+	return 15 * time.Second
+}
+
+// RetentionConfig controls how long health checks are kept. Raw checks are
+// rolled up into hourly and daily aggregates before they are deleted; daily
+// aggregates are kept forever.
+type RetentionConfig struct {
+	RawDays    int `yaml:"raw_days"`    // at least 2
+	HourlyDays int `yaml:"hourly_days"` // -1 keeps hourly aggregates forever
+}
+
 func LoadConfig() (*Config, error) {
 	// Read config file
 	data, err := os.ReadFile("config/config.yaml")
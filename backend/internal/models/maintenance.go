@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// MaintenanceWindow pauses alerting for the services it covers. Checks keep
+// running and are recorded, flagged as in maintenance.
+//
+// A one-off window has StartsAt and EndsAt. A recurring window opens each
+// time Schedule (a cron expression in Timezone) fires and stays open for
+// Duration minutes; StartsAt and EndsAt, when set, bound when it applies.
+// A window covers the services in ServiceIDs and those with any of Tags, or
+// every service when both are empty.
+type MaintenanceWindow struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	StartsAt   *time.Time `json:"startsAt,omitempty"`
+	EndsAt     *time.Time `json:"endsAt,omitempty"`
+	Schedule   string     `json:"schedule,omitempty"`
+	Timezone   string     `json:"timezone,omitempty"`
+	Duration   int        `json:"duration,omitempty"`
+	ServiceIDs []int64    `json:"serviceIds"`
+	Tags       []string   `json:"tags"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// MaintenancePeriod is one occurrence of a maintenance window.
+type MaintenancePeriod struct {
+	WindowID int64     `json:"windowId"`
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
 )
 
@@ -28,25 +30,137 @@ type EscalationChain struct {
 }
 
 type HealthCheck struct {
-	ID           int64     `json:"id" db:"id"`
-	ServiceID    int64     `json:"service_id" db:"service_id"`
-	Status       string    `json:"status" db:"status"`
-	ResponseTime int64     `json:"response_time" db:"response_time"` // in milliseconds
-	Error        string    `json:"error" db:"error"`
-	CheckedAt    time.Time `json:"checked_at" db:"checked_at"`
+	ID           int64        `json:"id" db:"id"`
+	ServiceID    int64        `json:"service_id" db:"service_id"`
+	Status       string       `json:"status" db:"status"`
+	ResponseTime int64        `json:"response_time" db:"response_time"` // in milliseconds
+	Error        string       `json:"error" db:"error"`
+	Details      CheckDetails `json:"details,omitempty" db:"details"`
+	Timing       *CheckTiming `json:"timing,omitempty" db:"timing"`
+	InMaintenance bool        `json:"in_maintenance" db:"in_maintenance"`
+	Warning      string       `json:"warning,omitempty" db:"-"` // not stored; set on checks just run
+	CheckedAt    time.Time    `json:"checked_at" db:"checked_at"`
+}
+
+// CheckDetails holds checker-specific measurements such as ping statistics.
+type CheckDetails map[string]interface{}
+
+func (d CheckDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+func (d *CheckDetails) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, d)
+}
+
+// CheckTiming breaks an HTTP check into phases, in milliseconds. Phases that
+// did not happen, such as DNS for an IP address, TLS for plain HTTP or any
+// phase after a failed connection, are left out. TTFB runs from the request
+// being sent to the first response byte, so DNS, Connect, TLS and TTFB
+// roughly add up to the response time, which ends at the response headers.
+// Transfer, reading the body, comes on top of it.
+type CheckTiming struct {
+	DNS      float64 `json:"dns,omitempty"`
+	Connect  float64 `json:"connect,omitempty"`
+	TLS      float64 `json:"tls,omitempty"`
+	TTFB     float64 `json:"ttfb,omitempty"`
+	Transfer float64 `json:"transfer,omitempty"`
+}
+
+func (t CheckTiming) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+func (t *CheckTiming) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+// HealthBucket aggregates the checks in one interval of health history.
+// Latency statistics, in milliseconds, cover successful checks only and are
+// nil when there were none.
+type HealthBucket struct {
+	Start           time.Time `json:"start"`
+	Count           int       `json:"count"`
+	Up              int       `json:"up"`
+	Degraded        int       `json:"degraded"`
+	Down            int       `json:"down"`
+	Unknown         int       `json:"unknown"`
+	AvgResponseTime *float64  `json:"avg_response_time"`
+	P50             *float64  `json:"p50"`
+	P95             *float64  `json:"p95"`
+	P99             *float64  `json:"p99"`
+	InMaintenance   bool      `json:"in_maintenance"` // any check in the bucket was
+}
+
+// Uptime reports a service's availability over a time range. Availability
+// is the percentage of checks that were up or degraded, leaving out checks
+// whose status was unknown and, when excluded, checks during maintenance.
+// Incidents and AlertDowntime come from critical alerts overlapping the
+// range.
+type Uptime struct {
+	ServiceID           int64        `json:"service_id"`
+	From                time.Time    `json:"from"`
+	To                  time.Time    `json:"to"`
+	ExcludesMaintenance bool         `json:"excludes_maintenance"`
+	TotalChecks         int          `json:"total_checks"`
+	UpChecks            int          `json:"up_checks"`
+	DownChecks          int          `json:"down_checks"`
+	UnknownChecks       int          `json:"unknown_checks"`
+	MaintenanceChecks   int          `json:"maintenance_checks"`
+	Availability        *float64     `json:"availability"` // nil without checks
+	Incidents           int          `json:"incidents"`
+	AlertDowntime       float64      `json:"alert_downtime"` // in seconds
+	SLOTarget           float64      `json:"slo_target,omitempty"`
+	ErrorBudget         *ErrorBudget `json:"error_budget,omitempty"`
+}
+
+// ErrorBudget compares the failed checks in the SLO period, the rolling
+// window ending at the uptime range's end, with the failures an SLO target
+// allows, whatever range the uptime itself covers. Remaining is the fraction
+// of the budget left, 0 once it is spent; BurnRate is the failure rate
+// relative to the allowed rate, so above 1 the budget is overspent.
+type ErrorBudget struct {
+	PeriodFrom time.Time `json:"period_from"`
+	PeriodTo   time.Time `json:"period_to"`
+	Allowed    float64   `json:"allowed"`
+	Consumed   int       `json:"consumed"`
+	Remaining  float64   `json:"remaining"`
+	BurnRate   float64   `json:"burn_rate"`
 }
 
 type Alert struct {
 	ID                int64     `json:"id" db:"id"`
 	ServiceID         int64     `json:"service_id" db:"service_id"`
 	Status            string    `json:"status" db:"status"`
+	Severity          string    `json:"severity" db:"severity"`
+	Message           string    `json:"message" db:"message"`
 	StartedAt         time.Time `json:"started_at" db:"started_at"`
-	ResolvedAt        time.Time `json:"resolved_at" db:"resolved_at"`
+	ResolvedAt        *time.Time `json:"resolved_at" db:"resolved_at"`
 	VerificationStatus string    `json:"verification_status" db:"verification_status"`
 	CreatedAt         time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Alert severities. Critical alerts are raised when a service goes down;
+// degraded alerts while it responds slower than its latency thresholds;
+// warnings flag problems that need attention before they cause an outage.
+const (
+	SeverityCritical = "critical"
+	SeverityDegraded = "degraded"
+	SeverityWarning  = "warning"
+)
+
 type AlertNotification struct {
 	ID          int64     `json:"id" db:"id"`
 	AlertID     int64     `json:"alert_id" db:"alert_id"`
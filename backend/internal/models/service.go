@@ -9,10 +9,17 @@ import (
 type ServiceType string
 
 const (
-	ServiceTypeHTTP    ServiceType = "http"
-	ServiceTypeTCP     ServiceType = "tcp"
-	ServiceTypeICMP    ServiceType = "icmp"
-	ServiceTypeCustom  ServiceType = "custom"
+	ServiceTypeHTTP        ServiceType = "http"
+	ServiceTypeTCP         ServiceType = "tcp"
+	ServiceTypeICMP        ServiceType = "icmp"
+	ServiceTypeCustom      ServiceType = "custom"
+	ServiceTypeTLS         ServiceType = "tls"
+	ServiceTypeDNS         ServiceType = "dns"
+	ServiceTypePostgres    ServiceType = "postgres"
+	ServiceTypeRedis       ServiceType = "redis"
+	ServiceTypeGRPC        ServiceType = "grpc"
+	ServiceTypeHeartbeat   ServiceType = "heartbeat"
+	ServiceTypeTransaction ServiceType = "transaction"
 )
 
 type ServiceConfig struct {
@@ -26,6 +33,102 @@ type ServiceConfig struct {
 	SuccessThreshold  int               `json:"successThreshold,omitempty"`
 	FailureThreshold  int               `json:"failureThreshold,omitempty"`
 	CustomScript      string            `json:"customScript,omitempty"`
+	SendString        string            `json:"sendString,omitempty"`
+	ExpectString      string            `json:"expectString,omitempty"`
+	PingCount         int               `json:"pingCount,omitempty"`
+	DegradedLoss      float64           `json:"degradedLoss,omitempty"` // packet loss percentage
+	DownLoss          float64           `json:"downLoss,omitempty"`     // packet loss percentage
+	AcceptedStatuses  []string          `json:"acceptedStatuses,omitempty"` // e.g. "200", "200-299", "3xx"
+	FollowRedirects   *bool             `json:"followRedirects,omitempty"`
+	TLSSkipVerify     bool              `json:"tlsSkipVerify,omitempty"`
+	CABundle          string            `json:"caBundle,omitempty"` // PEM encoded certificates
+	Assertions        []Assertion       `json:"assertions,omitempty"`
+	CertExpiryDays    int               `json:"certExpiryDays,omitempty"` // warn this many days before expiry
+	Resolver          string            `json:"resolver,omitempty"`   // host[:port], system resolver when empty
+	RecordType        string            `json:"recordType,omitempty"` // A, AAAA, CNAME, MX, TXT, SRV
+	ExpectedValues    []string          `json:"expectedValues,omitempty"`
+	Username          string            `json:"username,omitempty"`
+	Password          string            `json:"password,omitempty"`
+	Query             string            `json:"query,omitempty"` // probe query, or a Redis command split on whitespace
+	Command           []string          `json:"command,omitempty"` // Redis command and its arguments, e.g. ["GET", "a key"]
+	ExpectedResult    string            `json:"expectedResult,omitempty"`
+	GRPCService       string            `json:"grpcService,omitempty"` // service name sent in the health check request
+	GRPCTLS           bool              `json:"grpcTls,omitempty"`
+	Period            int               `json:"period,omitempty"` // seconds between expected heartbeat pings
+	Grace             int               `json:"grace,omitempty"`  // seconds a heartbeat ping may be late
+	Schedule          string            `json:"schedule,omitempty"` // cron expression, replaces CheckInterval when set
+	Timezone          string            `json:"timezone,omitempty"` // IANA zone for Schedule, UTC when empty
+	Steps             []TransactionStep `json:"steps,omitempty"`
+	DegradedResponseTime int            `json:"degradedResponseTime,omitempty"` // ms, a single slower check is degraded
+	DegradedP95       int               `json:"degradedP95,omitempty"`          // ms, degraded while the rolling p95 is slower
+	P95Window         int               `json:"p95Window,omitempty"`            // number of recent checks in the rolling p95
+	DegradedAlert     string            `json:"degradedAlert,omitempty"`        // alert policy while degraded, record by default
+	DownAlert         string            `json:"downAlert,omitempty"`            // alert policy while down, page by default
+	WarningAlert      string            `json:"warningAlert,omitempty"`         // alert policy for warnings, record by default
+	SLOTarget         float64           `json:"sloTarget,omitempty"`            // availability objective in percent, e.g. 99.9
+}
+
+// Alert policies decide how an alert is raised. With none no alert is
+// created; record creates it without notifying anyone; sms texts each level
+// of the escalation chain; page texts and then calls each level.
+const (
+	AlertPolicyNone   = "none"
+	AlertPolicyRecord = "record"
+	AlertPolicySMS    = "sms"
+	AlertPolicyPage   = "page"
+)
+
+// Assertion types evaluated against HTTP responses.
+const (
+	AssertBodyContains    = "bodyContains"
+	AssertBodyNotContains = "bodyNotContains"
+	AssertBodyRegex       = "bodyRegex"
+	AssertJSONPath        = "jsonPath"
+	AssertHeader          = "header"
+	AssertResponseTime    = "responseTime"
+)
+
+// Assertion is a check on an HTTP response. Path is the JSONPath expression
+// (e.g. "$.status") or header name; Operator is one of eq, ne, lt, lte, gt,
+// gte, contains or matches and defaults to eq (lte for responseTime, whose
+// Value is in milliseconds).
+type Assertion struct {
+	Type     string `json:"type"`
+	Path     string `json:"path,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+// TransactionStep is one request of a transaction check. URL may be
+// relative to the service URL. URL, header values and Body may reference
+// values extracted by earlier steps as {{name}}.
+type TransactionStep struct {
+	Name             string            `json:"name,omitempty"`
+	Method           string            `json:"method,omitempty"`
+	URL              string            `json:"url"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Body             string            `json:"body,omitempty"`
+	ExpectedStatus   int               `json:"expectedStatus,omitempty"`
+	AcceptedStatuses []string          `json:"acceptedStatuses,omitempty"`
+	Assertions       []Assertion       `json:"assertions,omitempty"`
+	Extract          []Extraction      `json:"extract,omitempty"`
+}
+
+// Extraction sources for transaction steps.
+const (
+	ExtractJSONPath = "jsonPath"
+	ExtractHeader   = "header"
+	ExtractRegex    = "regex"
+	ExtractCookie   = "cookie"
+)
+
+// Extraction stores a value from a step's response under Name. Path is the
+// JSONPath expression, header name, regex (its first capture group, or the
+// whole match without one) or cookie name.
+type Extraction struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Path   string `json:"path"`
 }
 
 func (c ServiceConfig) Value() (driver.Value, error) {
@@ -46,6 +149,55 @@ type Service struct {
 	Type      ServiceType   `json:"type"`
 	URL       string        `json:"url"`
 	Config    ServiceConfig `json:"config"`
+	State     *ServiceState `json:"state,omitempty"`
+	PingToken string        `json:"pingToken,omitempty"` // heartbeat services are pinged at /api/ping/:token; only returned on create and update
+	DependsOn []int64       `json:"dependsOn,omitempty"` // IDs of upstream services
+	Tags      []string      `json:"tags"`
 	CreatedAt time.Time     `json:"createdAt"`
 	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// Service states tracked across checks. A service only moves to down after
+// FailureThreshold consecutive failures and back to up after
+// SuccessThreshold consecutive successes.
+const (
+	StateUp         = "up"
+	StateSuspect    = "suspect"
+	StateDown       = "down"
+	StateRecovering = "recovering"
+)
+
+type ServiceState struct {
+	State                string    `json:"state"`
+	ConsecutiveSuccesses int       `json:"consecutiveSuccesses"`
+	ConsecutiveFailures  int       `json:"consecutiveFailures"`
+	LastStatus           string    `json:"lastStatus"`
+	LastCheckedAt        time.Time `json:"lastCheckedAt"`
+	ChangedAt            time.Time `json:"changedAt"`
+	// ImpactedBy is set while the service is down because an upstream
+	// dependency is down; its own alerts are suppressed meanwhile.
+	ImpactedBy *int64 `json:"impactedBy,omitempty"`
+	// AlertPending is set when the service went down during maintenance;
+	// its alert is raised if it is still down once the window closes.
+	AlertPending bool `json:"alertPending,omitempty"`
+}
+
+// DependencyGraph is every service with its state and the dependencies
+// between them. Edges point from a service to the service it depends on.
+type DependencyGraph struct {
+	Nodes []DependencyNode `json:"nodes"`
+	Edges []DependencyEdge `json:"edges"`
+}
+
+type DependencyNode struct {
+	ID         int64       `json:"id"`
+	Name       string      `json:"name"`
+	Type       ServiceType `json:"type"`
+	State      string      `json:"state,omitempty"`
+	ImpactedBy *int64      `json:"impactedBy,omitempty"`
+}
+
+type DependencyEdge struct {
+	ServiceID   int64 `json:"serviceId"`
+	DependsOnID int64 `json:"dependsOnId"`
 } 
\ No newline at end of file
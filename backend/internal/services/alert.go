@@ -4,52 +4,222 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"sync"
 	"time"
 	"service-monitor/internal/models"
 	"service-monitor/pkg/notifications"
 )
 
+const escalationPollInterval = 5 * time.Second
+
 type AlertService struct {
 	db            *sql.DB
 	notifyService *notifications.TwilioService
+	maintenance   *MaintenanceService
+	wake          chan struct{}
 }
 
 func NewAlertService(db *sql.DB, notifyService *notifications.TwilioService) *AlertService {
 	return &AlertService{
 		db:            db,
 		notifyService: notifyService,
+		maintenance:   NewMaintenanceService(db),
+		wake:          make(chan struct{}, 1),
 	}
 }
 
 func (s *AlertService) CreateAlert(ctx context.Context, serviceID int64) (*models.Alert, error) {
+	return s.CreateAlertWithSeverity(ctx, serviceID, models.SeverityCritical, "")
+}
+
+// CreateAlertWithSeverity creates an alert with a severity and an optional
+// message that replaces the default "is down" notification text. A service
+// has at most one unresolved alert per severity; if it already has one,
+// nothing is created and the alert returned is nil.
+func (s *AlertService) CreateAlertWithSeverity(ctx context.Context, serviceID int64, severity, message string) (*models.Alert, error) {
 	query := `
-		INSERT INTO alerts (service_id, status, verification_status)
-		VALUES ($1, 'active', 'pending')
-		RETURNING id, service_id, status, started_at, resolved_at, verification_status, created_at, updated_at
+		INSERT INTO alerts (service_id, status, severity, message, verification_status)
+		VALUES ($1, 'active', $2, $3, 'pending')
+		ON CONFLICT DO NOTHING
+		RETURNING id, service_id, status, severity, message, started_at, resolved_at, verification_status, created_at, updated_at
 	`
 
 	var alert models.Alert
-	err := s.db.QueryRowContext(ctx, query, serviceID).Scan(
+	err := s.db.QueryRowContext(ctx, query, serviceID, severity, message).Scan(
 		&alert.ID,
 		&alert.ServiceID,
 		&alert.Status,
+		&alert.Severity,
+		&alert.Message,
 		&alert.StartedAt,
 		&alert.ResolvedAt,
 		&alert.VerificationStatus,
 		&alert.CreatedAt,
 		&alert.UpdatedAt,
 	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create alert: %w", err)
 	}
 
-	// Start notification process
-	go s.startNotificationProcess(context.Background(), &alert)
+	// Notifications are sent by the leader running RunEscalations
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
 
 	return &alert, nil
 }
 
-func (s *AlertService) startNotificationProcess(ctx context.Context, alert *models.Alert) {
+// RunEscalations sends notifications for active alerts until ctx is
+// cancelled. It must only run on the elected leader: it claims alerts with
+// the leader's fencing token and stops escalating any alert that a leader
+// with a newer token has claimed. Alerts left part-way by a previous leader
+// resume at the level it reached.
+func (s *AlertService) RunEscalations(ctx context.Context, token int64) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(escalationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		alerts, err := s.claimAlerts(ctx, token)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim alerts for escalation: %v", err)
+		}
+		for _, alert := range alerts {
+			policy, err := s.serviceAlertPolicy(ctx, &alert.Alert)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Failed to get alert policy for alert %d: %v", alert.ID, err)
+				}
+				continue
+			}
+			// Recorded alerts are claimed but never escalated
+			if !notifies(policy) {
+				continue
+			}
+
+			wg.Add(1)
+			go func(alert *claimedAlert) {
+				defer wg.Done()
+				s.startNotificationProcess(ctx, &alert.Alert, policy, token, alert.level)
+			}(alert)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+type claimedAlert struct {
+	models.Alert
+	level int
+}
+
+// claimAlerts takes over every active alert not already escalated under
+// token or a newer one.
+func (s *AlertService) claimAlerts(ctx context.Context, token int64) ([]*claimedAlert, error) {
+	query := `
+		UPDATE alerts
+		SET escalation_token = $1
+		WHERE status = 'active' AND (escalation_token IS NULL OR escalation_token < $1)
+		RETURNING id, service_id, status, severity, message, started_at, resolved_at, verification_status, created_at, updated_at, escalation_level
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []*claimedAlert
+	for rows.Next() {
+		var alert claimedAlert
+		err := rows.Scan(
+			&alert.ID,
+			&alert.ServiceID,
+			&alert.Status,
+			&alert.Severity,
+			&alert.Message,
+			&alert.StartedAt,
+			&alert.ResolvedAt,
+			&alert.VerificationStatus,
+			&alert.CreatedAt,
+			&alert.UpdatedAt,
+			&alert.level,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, &alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// holdsEscalation records that the alert is being escalated at level and
+// reports whether this leader may still notify for it: false once the alert
+// is resolved or a newer leader has claimed it.
+func (s *AlertService) holdsEscalation(ctx context.Context, alertID, token int64, level int) bool {
+	query := `
+		UPDATE alerts
+		SET escalation_level = $3
+		WHERE id = $1 AND escalation_token = $2 AND status = 'active'
+	`
+
+	result, err := s.db.ExecContext(ctx, query, alertID, token, level)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to update escalation for alert %d: %v", alertID, err)
+		}
+		return false
+	}
+	rows, err := result.RowsAffected()
+	return err == nil && rows == 1
+}
+
+// awaitMaintenance holds escalation while the service is in a maintenance
+// window. It returns false if ctx is cancelled first.
+func (s *AlertService) awaitMaintenance(ctx context.Context, serviceID int64) bool {
+	for {
+		window, err := s.maintenance.ActiveWindow(ctx, serviceID, time.Now())
+		if err != nil {
+			// Better to page during maintenance than to miss an outage
+			log.Printf("Failed to check maintenance for service %d: %v", serviceID, err)
+			return ctx.Err() == nil
+		}
+		if window == nil {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(maintenancePollInterval):
+		}
+	}
+}
+
+// serviceAlertPolicy returns the alert policy of the alert's service for
+// the alert's severity.
+func (s *AlertService) serviceAlertPolicy(ctx context.Context, alert *models.Alert) (string, error) {
+	var cfg models.ServiceConfig
+	if err := s.db.QueryRowContext(ctx, `SELECT config FROM services WHERE id = $1`, alert.ServiceID).Scan(&cfg); err != nil {
+		return "", fmt.Errorf("failed to get service config: %w", err)
+	}
+	return alertPolicy(cfg, alert.Severity), nil
+}
+
+func (s *AlertService) startNotificationProcess(ctx context.Context, alert *models.Alert, policy string, token int64, level int) {
 	// Get escalation chain
 	chain, err := s.getEscalationChain(ctx, alert.ServiceID)
 	if err != nil {
@@ -57,13 +227,25 @@ func (s *AlertService) startNotificationProcess(ctx context.Context, alert *mode
 		return
 	}
 
-	// Start with first level
-	currentLevel := 1
+	message := fmt.Sprintf("Service alert: Service ID %d is down", alert.ServiceID)
+	if alert.Message != "" {
+		message = fmt.Sprintf("Service alert: Service ID %d: %s", alert.ServiceID, alert.Message)
+	}
+
+	// Start with first level, or where the previous leader stopped
+	currentLevel := level
+	if currentLevel < 1 {
+		currentLevel = 1
+	}
 	for currentLevel <= len(chain) {
 		user := chain[currentLevel-1]
-		
+
+		if !s.awaitMaintenance(ctx, alert.ServiceID) || !s.holdsEscalation(ctx, alert.ID, token, currentLevel) {
+			return
+		}
+
 		// Try SMS first
-		if err := s.notifyService.SendSMS(user.Phone, fmt.Sprintf("Service alert: Service ID %d is down", alert.ServiceID)); err != nil {
+		if err := s.notifyService.SendSMS(user.Phone, message); err != nil {
 			// Log error and continue
 		}
 
@@ -74,14 +256,19 @@ func (s *AlertService) startNotificationProcess(ctx context.Context, alert *mode
 		}
 
 		// Try voice call
-		if err := s.notifyService.MakeCall(user.Phone, fmt.Sprintf("Service alert: Service ID %d is down", alert.ServiceID)); err != nil {
-			// Log error and continue
-		}
+		if policy == models.AlertPolicyPage {
+			if !s.awaitMaintenance(ctx, alert.ServiceID) || !s.holdsEscalation(ctx, alert.ID, token, currentLevel) {
+				return
+			}
+			if err := s.notifyService.MakeCall(user.Phone, message); err != nil {
+				// Log error and continue
+			}
 
-		// Wait for response or timeout
-		responded = s.waitForResponse(ctx, alert.ID, user.ID, 5*time.Minute)
-		if responded {
-			return
+			// Wait for response or timeout
+			responded = s.waitForResponse(ctx, alert.ID, user.ID, 5*time.Minute)
+			if responded {
+				return
+			}
 		}
 
 		// Move to next level
@@ -126,8 +313,12 @@ func (s *AlertService) getEscalationChain(ctx context.Context, serviceID int64)
 }
 
 func (s *AlertService) waitForResponse(ctx context.Context, alertID, userID int64, timeout time.Duration) bool {
+	// Stop polling once the timeout has passed
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	// Create a channel to receive the response
-	responseChan := make(chan bool)
+	responseChan := make(chan bool, 1)
 	
 	// Start a goroutine to check for response
 	go func() {
@@ -182,6 +373,57 @@ func (s *AlertService) ResolveAlert(ctx context.Context, alertID int64) error {
 	return nil
 }
 
+// GetActiveAlert returns the service's unresolved alert of the given
+// severity, or nil if there is none.
+func (s *AlertService) GetActiveAlert(ctx context.Context, serviceID int64, severity string) (*models.Alert, error) {
+	query := `
+		SELECT id, service_id, status, severity, message, started_at, resolved_at, verification_status, created_at, updated_at
+		FROM alerts
+		WHERE service_id = $1 AND status = 'active' AND severity = $2
+		ORDER BY started_at DESC
+		LIMIT 1
+	`
+
+	var alert models.Alert
+	err := s.db.QueryRowContext(ctx, query, serviceID, severity).Scan(
+		&alert.ID,
+		&alert.ServiceID,
+		&alert.Status,
+		&alert.Severity,
+		&alert.Message,
+		&alert.StartedAt,
+		&alert.ResolvedAt,
+		&alert.VerificationStatus,
+		&alert.CreatedAt,
+		&alert.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active alert: %w", err)
+	}
+
+	return &alert, nil
+}
+
+// ResolveServiceAlerts resolves every active alert of the given severity for
+// a service.
+func (s *AlertService) ResolveServiceAlerts(ctx context.Context, serviceID int64, severity string) error {
+	query := `
+		UPDATE alerts
+		SET status = 'resolved', resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE service_id = $1 AND status = 'active' AND severity = $2
+	`
+
+	_, err := s.db.ExecContext(ctx, query, serviceID, severity)
+	if err != nil {
+		return fmt.Errorf("failed to resolve service alerts: %w", err)
+	}
+
+	return nil
+}
+
 func (s *AlertService) VerifyAlert(ctx context.Context, alertID int64) error {
 	query := `
 		UPDATE alerts
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"service-monitor/internal/models"
+)
+
+// assertionResult describes one evaluated assertion for health check details.
+type assertionResult struct {
+	Assertion models.Assertion `json:"assertion"`
+	Passed    bool             `json:"passed"`
+	Actual    string           `json:"actual,omitempty"`
+	Message   string           `json:"message,omitempty"`
+}
+
+// httpResponse is what assertions are evaluated against.
+type httpResponse struct {
+	Header       http.Header
+	Body         []byte
+	ResponseTime int64
+}
+
+// evaluateAssertions runs every assertion against the response and returns
+// all results plus a description of each failure.
+func evaluateAssertions(assertions []models.Assertion, resp *httpResponse) ([]assertionResult, []string) {
+	results := make([]assertionResult, 0, len(assertions))
+	var failures []string
+
+	var parsedBody interface{}
+	var parseErr error
+	parsed := false
+
+	for _, assertion := range assertions {
+		result := assertionResult{Assertion: assertion}
+
+		var err error
+		switch assertion.Type {
+		case models.AssertBodyContains:
+			result.Passed = strings.Contains(string(resp.Body), assertion.Value)
+			if !result.Passed {
+				result.Message = fmt.Sprintf("body does not contain %q", assertion.Value)
+			}
+		case models.AssertBodyNotContains:
+			result.Passed = !strings.Contains(string(resp.Body), assertion.Value)
+			if !result.Passed {
+				result.Message = fmt.Sprintf("body contains %q", assertion.Value)
+			}
+		case models.AssertBodyRegex:
+			var re *regexp.Regexp
+			re, err = regexp.Compile(assertion.Value)
+			if err == nil {
+				result.Passed = re.Match(resp.Body)
+				if !result.Passed {
+					result.Message = fmt.Sprintf("body does not match /%s/", assertion.Value)
+				}
+			}
+		case models.AssertJSONPath:
+			if !parsed {
+				parseErr = json.Unmarshal(resp.Body, &parsedBody)
+				parsed = true
+			}
+			if parseErr != nil {
+				err = fmt.Errorf("body is not valid JSON: %v", parseErr)
+				break
+			}
+			var value interface{}
+			value, err = evalJSONPath(parsedBody, assertion.Path)
+			if err == nil {
+				result.Actual = jsonString(value)
+				result.Passed, err = compare(result.Actual, assertion.Operator, assertion.Value)
+				if err == nil && !result.Passed {
+					result.Message = fmt.Sprintf("%s is %s, expected %s %s",
+						assertion.Path, result.Actual, operatorOrDefault(assertion.Operator, "eq"), assertion.Value)
+				}
+			}
+		case models.AssertHeader:
+			values, ok := resp.Header[http.CanonicalHeaderKey(assertion.Path)]
+			if !ok {
+				result.Message = fmt.Sprintf("header %s is missing", assertion.Path)
+				break
+			}
+			result.Actual = strings.Join(values, ", ")
+			result.Passed, err = compare(result.Actual, assertion.Operator, assertion.Value)
+			if err == nil && !result.Passed {
+				result.Message = fmt.Sprintf("header %s is %q, expected %s %q",
+					assertion.Path, result.Actual, operatorOrDefault(assertion.Operator, "eq"), assertion.Value)
+			}
+		case models.AssertResponseTime:
+			operator := operatorOrDefault(assertion.Operator, "lte")
+			result.Actual = strconv.FormatInt(resp.ResponseTime, 10)
+			result.Passed, err = compare(result.Actual, operator, assertion.Value)
+			if err == nil && !result.Passed {
+				result.Message = fmt.Sprintf("response time %dms, expected %s %sms", resp.ResponseTime, operator, assertion.Value)
+			}
+		default:
+			err = fmt.Errorf("unknown assertion type %q", assertion.Type)
+		}
+
+		if err != nil {
+			result.Passed = false
+			result.Message = err.Error()
+		}
+		if !result.Passed {
+			failures = append(failures, result.Message)
+		}
+		results = append(results, result)
+	}
+
+	return results, failures
+}
+
+// validateAssertions rejects unknown assertion types, invalid regexes and
+// malformed JSONPath expressions.
+func validateAssertions(assertions []models.Assertion) error {
+	for _, assertion := range assertions {
+		switch assertion.Type {
+		case models.AssertBodyContains, models.AssertBodyNotContains, models.AssertResponseTime:
+		case models.AssertBodyRegex:
+			if _, err := regexp.Compile(assertion.Value); err != nil {
+				return fmt.Errorf("invalid assertion regex %q: %v", assertion.Value, err)
+			}
+		case models.AssertJSONPath:
+			if _, err := parseJSONPath(assertion.Path); err != nil {
+				return err
+			}
+		case models.AssertHeader:
+			if assertion.Path == "" {
+				return fmt.Errorf("header assertion requires a header name in path")
+			}
+		default:
+			return fmt.Errorf("unknown assertion type %q", assertion.Type)
+		}
+
+		switch assertion.Operator {
+		case "", "eq", "ne", "lt", "lte", "gt", "gte", "contains":
+		case "matches":
+			if _, err := regexp.Compile(assertion.Value); err != nil {
+				return fmt.Errorf("invalid assertion regex %q: %v", assertion.Value, err)
+			}
+		default:
+			return fmt.Errorf("unknown assertion operator %q", assertion.Operator)
+		}
+	}
+
+	return nil
+}
+
+func operatorOrDefault(operator, fallback string) string {
+	if operator == "" {
+		return fallback
+	}
+	return operator
+}
+
+// compare applies operator to actual and expected. Ordering operators compare
+// numerically; eq and ne compare numerically when both sides are numbers and
+// as strings otherwise.
+func compare(actual, operator, expected string) (bool, error) {
+	actualNum, actualErr := strconv.ParseFloat(actual, 64)
+	expectedNum, expectedErr := strconv.ParseFloat(expected, 64)
+	numeric := actualErr == nil && expectedErr == nil
+
+	switch operatorOrDefault(operator, "eq") {
+	case "eq":
+		if numeric {
+			return actualNum == expectedNum, nil
+		}
+		return actual == expected, nil
+	case "ne":
+		if numeric {
+			return actualNum != expectedNum, nil
+		}
+		return actual != expected, nil
+	case "contains":
+		return strings.Contains(actual, expected), nil
+	case "matches":
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return false, fmt.Errorf("invalid regex %q: %v", expected, err)
+		}
+		return re.MatchString(actual), nil
+	case "lt", "lte", "gt", "gte":
+		if !numeric {
+			return false, fmt.Errorf("cannot compare %q and %q numerically", actual, expected)
+		}
+		switch operator {
+		case "lt":
+			return actualNum < expectedNum, nil
+		case "lte":
+			return actualNum <= expectedNum, nil
+		case "gt":
+			return actualNum > expectedNum, nil
+		default:
+			return actualNum >= expectedNum, nil
+		}
+	}
+
+	return false, fmt.Errorf("unknown operator %q", operator)
+}
+
+// evalJSONPath resolves a simple JSONPath expression such as
+// "$.data.items[0].status" or "$['status']" against decoded JSON.
+func evalJSONPath(document interface{}, path string) (interface{}, error) {
+	tokens, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := document
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%s: key %q not found", path, token)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not an array index", path, token)
+			}
+			if index < 0 {
+				index += len(node)
+			}
+			if index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("%s: index %s out of range", path, token)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%s: cannot descend into %s", path, jsonString(current))
+		}
+	}
+
+	return current, nil
+}
+
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	if path != "" && path[0] != '.' && path[0] != '[' {
+		path = "." + path
+	}
+
+	var tokens []string
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSONPath: empty key")
+			}
+			tokens = append(tokens, path[:end])
+			path = path[end:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath: unclosed bracket")
+			}
+			token := strings.Trim(path[1:end], `'"`)
+			tokens = append(tokens, token)
+			path = path[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath near %q", path)
+		}
+	}
+
+	return tokens, nil
+}
+
+// jsonString renders a decoded JSON value for comparison. Strings are used
+// verbatim and everything else is re-encoded.
+func jsonString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"service-monitor/internal/models"
+)
+
+const testBody = `{"status":"ok","version":"1.2.0","data":{"items":[{"id":1,"status":"up"},{"id":2,"status":"down"}],"count":2,"ok":true}}`
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{path: "$", want: nil},
+		{path: "$.status", want: []string{"status"}},
+		{path: "status", want: []string{"status"}},
+		{path: "$.data.items[0].status", want: []string{"data", "items", "0", "status"}},
+		{path: "$['status']", want: []string{"status"}},
+		{path: `$["data"]["count"]`, want: []string{"data", "count"}},
+		{path: "$.data.items[-1]", want: []string{"data", "items", "-1"}},
+		{path: "$..status", wantErr: true},
+		{path: "$.data.", wantErr: true},
+		{path: "$.items[0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSONPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJSONPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(testBody), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "$.status", want: "ok"},
+		{path: "$.data.count", want: "2"},
+		{path: "$.data.ok", want: "true"},
+		{path: "$.data.items[1].status", want: "down"},
+		{path: "$.data.items[-1].id", want: "2"},
+		{path: "$.data.items[0]", want: `{"id":1,"status":"up"}`},
+		{path: "$.missing", wantErr: true},
+		{path: "$.data.items[2]", wantErr: true},
+		{path: "$.data.items.first", wantErr: true},
+		{path: "$.status.value", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			value, err := evalJSONPath(document, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evalJSONPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if got := jsonString(value); !tt.wantErr && got != tt.want {
+				t.Errorf("evalJSONPath(%q) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		actual, operator, expected string
+		want                       bool
+		wantErr                    bool
+	}{
+		{actual: "ok", expected: "ok", want: true},
+		{actual: "1.0", operator: "eq", expected: "1", want: true},
+		{actual: "1.2.0", operator: "eq", expected: "1.2", want: false},
+		{actual: "ok", operator: "ne", expected: "fail", want: true},
+		{actual: "2", operator: "ne", expected: "2.0", want: false},
+		{actual: "9", operator: "lt", expected: "10", want: true},
+		{actual: "10", operator: "lte", expected: "10", want: true},
+		{actual: "10", operator: "gt", expected: "10", want: false},
+		{actual: "10", operator: "gte", expected: "10", want: true},
+		{actual: "healthy", operator: "contains", expected: "health", want: true},
+		{actual: "v1.2.0", operator: "matches", expected: `^v1\.`, want: true},
+		{actual: "ok", operator: "lt", expected: "10", wantErr: true},
+		{actual: "ok", operator: "matches", expected: "(", wantErr: true},
+		{actual: "ok", operator: "like", expected: "ok", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.actual+" "+tt.operator+" "+tt.expected, func(t *testing.T) {
+			got, err := compare(tt.actual, tt.operator, tt.expected)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compare error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("compare = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateAssertions(t *testing.T) {
+	resp := &httpResponse{
+		Header:       http.Header{"Content-Type": {"application/json"}, "X-Version": {"1.2.0"}},
+		Body:         []byte(testBody),
+		ResponseTime: 120,
+	}
+
+	tests := []struct {
+		name      string
+		assertion models.Assertion
+		passed    bool
+		actual    string
+	}{
+		{"body contains", models.Assertion{Type: models.AssertBodyContains, Value: `"status":"ok"`}, true, ""},
+		{"body contains missing", models.Assertion{Type: models.AssertBodyContains, Value: "error"}, false, ""},
+		{"body not contains", models.Assertion{Type: models.AssertBodyNotContains, Value: "error"}, true, ""},
+		{"body not contains present", models.Assertion{Type: models.AssertBodyNotContains, Value: "down"}, false, ""},
+		{"body regex", models.Assertion{Type: models.AssertBodyRegex, Value: `"count":\d+`}, true, ""},
+		{"body regex invalid", models.Assertion{Type: models.AssertBodyRegex, Value: "("}, false, ""},
+		{"json path", models.Assertion{Type: models.AssertJSONPath, Path: "$.data.items[0].status", Value: "up"}, true, "up"},
+		{"json path numeric", models.Assertion{Type: models.AssertJSONPath, Path: "$.data.count", Operator: "gte", Value: "2"}, true, "2"},
+		{"json path mismatch", models.Assertion{Type: models.AssertJSONPath, Path: "$.status", Value: "fail"}, false, "ok"},
+		{"json path missing", models.Assertion{Type: models.AssertJSONPath, Path: "$.missing", Value: "ok"}, false, ""},
+		{"header", models.Assertion{Type: models.AssertHeader, Path: "x-version", Value: "1.2.0"}, true, "1.2.0"},
+		{"header contains", models.Assertion{Type: models.AssertHeader, Path: "Content-Type", Operator: "contains", Value: "json"}, true, "application/json"},
+		{"header missing", models.Assertion{Type: models.AssertHeader, Path: "X-Missing", Value: "1"}, false, ""},
+		{"response time default lte", models.Assertion{Type: models.AssertResponseTime, Value: "200"}, true, "120"},
+		{"response time too slow", models.Assertion{Type: models.AssertResponseTime, Value: "100"}, false, "120"},
+		{"unknown type", models.Assertion{Type: "statusCode", Value: "200"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, failures := evaluateAssertions([]models.Assertion{tt.assertion}, resp)
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			result := results[0]
+			if result.Passed != tt.passed {
+				t.Errorf("passed = %v, want %v (%s)", result.Passed, tt.passed, result.Message)
+			}
+			if result.Actual != tt.actual {
+				t.Errorf("actual = %q, want %q", result.Actual, tt.actual)
+			}
+			if tt.passed != (len(failures) == 0) {
+				t.Errorf("failures = %q", failures)
+			}
+			if !tt.passed && result.Message == "" {
+				t.Error("failed assertion has no message")
+			}
+		})
+	}
+}
+
+func TestEvaluateAssertionsInvalidJSON(t *testing.T) {
+	assertions := []models.Assertion{
+		{Type: models.AssertJSONPath, Path: "$.status", Value: "ok"},
+		{Type: models.AssertBodyContains, Value: "status"},
+	}
+	resp := &httpResponse{Body: []byte("status: ok")}
+
+	results, failures := evaluateAssertions(assertions, resp)
+	if results[0].Passed || !results[1].Passed {
+		t.Errorf("passed = %v, %v, want false, true", results[0].Passed, results[1].Passed)
+	}
+	if len(failures) != 1 {
+		t.Errorf("failures = %q, want one", failures)
+	}
+}
+
+func TestValidateAssertions(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion models.Assertion
+		wantErr   bool
+	}{
+		{"body contains", models.Assertion{Type: models.AssertBodyContains, Value: "ok"}, false},
+		{"json path", models.Assertion{Type: models.AssertJSONPath, Path: "$.status", Operator: "ne", Value: "down"}, false},
+		{"header matches", models.Assertion{Type: models.AssertHeader, Path: "Server", Operator: "matches", Value: "^nginx"}, false},
+		{"unknown type", models.Assertion{Type: "statusCode"}, true},
+		{"invalid regex", models.Assertion{Type: models.AssertBodyRegex, Value: "("}, true},
+		{"invalid json path", models.Assertion{Type: models.AssertJSONPath, Path: "$.items[0"}, true},
+		{"header without name", models.Assertion{Type: models.AssertHeader, Value: "1"}, true},
+		{"unknown operator", models.Assertion{Type: models.AssertResponseTime, Operator: "below", Value: "100"}, true},
+		{"invalid matches regex", models.Assertion{Type: models.AssertHeader, Path: "Server", Operator: "matches", Value: "("}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAssertions([]models.Assertion{tt.assertion})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAssertions error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis keys share a hash tag so the scripts below work on Redis Cluster.
+const (
+	queuePendingKey    = "{checks}:pending"    // list of service IDs waiting for a worker
+	queueProcessingKey = "{checks}:processing" // sorted set of leased service IDs by lease expiry
+	queueJobsKey       = "{checks}:jobs"       // hash of service ID to enqueue time
+	queueDuePrefix     = "{checks}:due:"       // per-service marker while a check is not yet due again
+)
+
+var enqueueScript = redis.NewScript(`
+if not redis.call('SET', KEYS[3], '1', 'NX', 'PX', ARGV[3]) then
+	return 0
+end
+if redis.call('HSETNX', KEYS[2], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('LPUSH', KEYS[1], ARGV[1])
+return 1
+`)
+
+var dequeueScript = redis.NewScript(`
+local id = redis.call('RPOP', KEYS[1])
+if not id then
+	return false
+end
+redis.call('ZADD', KEYS[2], ARGV[1], id)
+return id
+`)
+
+var requeueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('RPUSH', KEYS[1], id)
+end
+return #ids
+`)
+
+// CheckQueue is a Redis-backed queue of service checks shared by the
+// scheduler and any number of worker processes. Each service has at most one
+// job queued or in flight. Dequeued jobs are leased; a job whose lease
+// expires, because its worker crashed, is put back at the head of the queue
+// by RequeueExpired.
+type CheckQueue struct {
+	client *redis.Client
+	lease  time.Duration
+}
+
+func NewCheckQueue(client *redis.Client, lease time.Duration) *CheckQueue {
+	return &CheckQueue{client: client, lease: lease}
+}
+
+// Enqueue queues a check for a service unless one is already queued, in
+// flight, or was enqueued less than interval ago by any scheduler.
+func (q *CheckQueue) Enqueue(ctx context.Context, serviceID int64, interval time.Duration) (bool, error) {
+	due := interval - time.Second
+	if due < time.Second {
+		due = time.Second
+	}
+
+	id := strconv.FormatInt(serviceID, 10)
+	added, err := enqueueScript.Run(ctx, q.client,
+		[]string{queuePendingKey, queueJobsKey, queueDuePrefix + id},
+		id, time.Now().Unix(), due.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue check: %w", err)
+	}
+
+	return added == 1, nil
+}
+
+// Dequeue leases the next queued check and returns its service ID. ok is
+// false when the queue is empty.
+func (q *CheckQueue) Dequeue(ctx context.Context) (serviceID int64, ok bool, err error) {
+	id, err := dequeueScript.Run(ctx, q.client,
+		[]string{queuePendingKey, queueProcessingKey},
+		q.leaseExpiry(),
+	).Text()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to dequeue check: %w", err)
+	}
+
+	serviceID, err = strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		// Drop malformed entries so they can't block the queue
+		q.remove(ctx, id)
+		return 0, false, fmt.Errorf("invalid job id %q in check queue", id)
+	}
+
+	return serviceID, true, nil
+}
+
+// ExtendLease keeps a long-running check from being handed to another worker.
+func (q *CheckQueue) ExtendLease(ctx context.Context, serviceID int64) error {
+	err := q.client.ZAddXX(ctx, queueProcessingKey, &redis.Z{
+		Score:  q.leaseExpiry(),
+		Member: strconv.FormatInt(serviceID, 10),
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+
+	return nil
+}
+
+// Complete removes a finished check from the queue.
+func (q *CheckQueue) Complete(ctx context.Context, serviceID int64) error {
+	return q.remove(ctx, strconv.FormatInt(serviceID, 10))
+}
+
+func (q *CheckQueue) remove(ctx context.Context, id string) error {
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, queueProcessingKey, id)
+	pipe.HDel(ctx, queueJobsKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to complete check: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueExpired returns checks whose lease has run out to the queue and
+// reports how many there were.
+func (q *CheckQueue) RequeueExpired(ctx context.Context) (int, error) {
+	count, err := requeueScript.Run(ctx, q.client,
+		[]string{queuePendingKey, queueProcessingKey},
+		float64(time.Now().UnixMilli()),
+	).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue expired checks: %w", err)
+	}
+
+	return count, nil
+}
+
+// Lease returns how long a dequeued check is reserved for its worker.
+func (q *CheckQueue) Lease() time.Duration {
+	return q.lease
+}
+
+func (q *CheckQueue) leaseExpiry() float64 {
+	return float64(time.Now().Add(q.lease).UnixMilli())
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"service-monitor/internal/models"
+)
+
+// Checker probes services of one ServiceType. Check must honour ctx and the
+// service's configured timeout and never record anything itself; retries,
+// storage, state tracking and alerting are handled by HealthCheckService.
+type Checker interface {
+	Check(ctx context.Context, service *models.Service) *CheckResult
+
+	// Validate rejects URLs and configs the checker cannot work with, so
+	// mistakes surface when the service is saved rather than on first check.
+	Validate(service *models.Service) error
+}
+
+// CheckerFuncs adapts a pair of functions into a Checker. A nil validate
+// accepts every config.
+type CheckerFuncs struct {
+	CheckFunc    func(ctx context.Context, service *models.Service) *CheckResult
+	ValidateFunc func(service *models.Service) error
+}
+
+func (c CheckerFuncs) Check(ctx context.Context, service *models.Service) *CheckResult {
+	return c.CheckFunc(ctx, service)
+}
+
+func (c CheckerFuncs) Validate(service *models.Service) error {
+	if c.ValidateFunc == nil {
+		return nil
+	}
+	return c.ValidateFunc(service)
+}
+
+var (
+	checkersMu sync.RWMutex
+	checkers   = make(map[models.ServiceType]Checker)
+)
+
+// RegisterChecker makes a checker available for a service type. Built-in
+// checkers are registered by this package; additional ones can be registered
+// from an init function in another package compiled into the binary.
+// Registering a type twice replaces the earlier checker.
+func RegisterChecker(serviceType models.ServiceType, checker Checker) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+
+	if checker == nil {
+		panic(fmt.Sprintf("services: RegisterChecker checker for %q is nil", serviceType))
+	}
+	checkers[models.ServiceType(strings.ToLower(string(serviceType)))] = checker
+}
+
+// LookupChecker returns the checker registered for a service type. Types are
+// matched case-insensitively and services without a type are treated as
+// HTTP, matching how they were always checked.
+func LookupChecker(serviceType models.ServiceType) (Checker, bool) {
+	serviceType = models.ServiceType(strings.ToLower(string(serviceType)))
+	if serviceType == "" {
+		serviceType = models.ServiceTypeHTTP
+	}
+
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+
+	checker, ok := checkers[serviceType]
+	return checker, ok
+}
+
+// CheckerTypes lists the registered service types in sorted order.
+func CheckerTypes() []models.ServiceType {
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+
+	types := make([]models.ServiceType, 0, len(checkers))
+	for serviceType := range checkers {
+		types = append(types, serviceType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return types
+}
+
+// ValidateService checks that a checker exists for the service's type, that
+// the checker accepts its URL and config and that any cron schedule and
+// alerting settings are valid.
+func ValidateService(service *models.Service) error {
+	checker, ok := LookupChecker(service.Type)
+	if !ok {
+		return fmt.Errorf("unsupported service type %q", service.Type)
+	}
+	if _, err := serviceSchedule(service); err != nil {
+		return err
+	}
+	if err := validateAlerting(service); err != nil {
+		return err
+	}
+	if err := validateSLO(service); err != nil {
+		return err
+	}
+	return checker.Validate(service)
+}
+
+func init() {
+	RegisterChecker(models.ServiceTypeHTTP, CheckerFuncs{probeHTTP, validateHTTP})
+	RegisterChecker(models.ServiceTypeTCP, CheckerFuncs{probeTCP, validateTCP})
+	RegisterChecker(models.ServiceTypeICMP, CheckerFuncs{probeICMP, validateICMP})
+	RegisterChecker(models.ServiceTypeCustom, CheckerFuncs{scriptsDisabled, validateScriptsDisabled})
+	RegisterChecker(models.ServiceTypeTLS, CheckerFuncs{probeTLS, validateTLS})
+	RegisterChecker(models.ServiceTypeDNS, CheckerFuncs{probeDNS, validateDNS})
+	RegisterChecker(models.ServiceTypePostgres, CheckerFuncs{probePostgres, validatePostgres})
+	RegisterChecker(models.ServiceTypeRedis, CheckerFuncs{probeRedis, validateRedis})
+	RegisterChecker(models.ServiceTypeGRPC, CheckerFuncs{probeGRPC, validateGRPC})
+	RegisterChecker(models.ServiceTypeTransaction, CheckerFuncs{probeTransaction, validateTransaction})
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"service-monitor/internal/models"
+)
+
+// maxScheduleRuns bounds how many run times NextRuns returns.
+const maxScheduleRuns = 100
+
+// parseSchedule parses a standard five-field cron expression, or a
+// descriptor such as @daily, evaluated in timezone (UTC when empty).
+func parseSchedule(expression, timezone string) (cron.Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	if strings.HasPrefix(expression, "TZ=") || strings.HasPrefix(expression, "CRON_TZ=") {
+		return nil, fmt.Errorf("set the timezone separately instead of in the cron expression")
+	}
+
+	schedule, err := cron.ParseStandard("CRON_TZ=" + timezone + " " + expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never runs", expression)
+	}
+
+	return schedule, nil
+}
+
+// serviceSchedule returns the service's cron schedule, or nil when it is
+// checked on a fixed interval.
+func serviceSchedule(service *models.Service) (cron.Schedule, error) {
+	if strings.TrimSpace(service.Config.Schedule) == "" {
+		return nil, nil
+	}
+	return parseSchedule(service.Config.Schedule, service.Config.Timezone)
+}
+
+// NextRuns returns the next count times a cron expression fires after from,
+// in the schedule's timezone.
+func NextRuns(expression, timezone string, from time.Time, count int) ([]time.Time, error) {
+	schedule, err := parseSchedule(expression, timezone)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 || count > maxScheduleRuns {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxScheduleRuns)
+	}
+
+	loc, _ := time.LoadLocation(timezone)
+	runs := make([]time.Time, 0, count)
+	next := from
+	for len(runs) < count {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next.In(loc))
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		timezone   string
+		wantErr    bool
+	}{
+		{"five fields", "*/5 * * * *", "", false},
+		{"descriptor", "@daily", "", false},
+		{"surrounding space", "  0 3 * * 1  ", "Europe/Berlin", false},
+		{"timezone", "0 9 * * 1-5", "America/New_York", false},
+		{"unknown timezone", "0 9 * * *", "Mars/Olympus", true},
+		{"timezone in expression", "CRON_TZ=UTC 0 9 * * *", "", true},
+		{"legacy timezone in expression", "TZ=UTC 0 9 * * *", "", true},
+		{"too few fields", "0 9 * *", "", true},
+		{"seconds field", "0 0 9 * * *", "", true},
+		{"out of range", "0 25 * * *", "", true},
+		{"never runs", "0 0 30 2 *", "", true},
+		{"empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSchedule(tt.expression, tt.timezone)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSchedule(%q, %q) error = %v, wantErr %v", tt.expression, tt.timezone, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextRuns(t *testing.T) {
+	from := time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		expression string
+		timezone   string
+		count      int
+		want       []string
+	}{
+		{
+			name:       "every quarter hour",
+			expression: "*/15 * * * *",
+			count:      3,
+			want:       []string{"2024-03-29T12:15:00Z", "2024-03-29T12:30:00Z", "2024-03-29T12:45:00Z"},
+		},
+		{
+			name:       "weekdays only",
+			expression: "0 9 * * 1-5",
+			count:      2,
+			want:       []string{"2024-04-01T09:00:00Z", "2024-04-02T09:00:00Z"},
+		},
+		{
+			name:       "in timezone across DST change",
+			expression: "0 3 * * *",
+			timezone:   "Europe/Berlin",
+			count:      3,
+			want:       []string{"2024-03-30T03:00:00+01:00", "2024-03-31T03:00:00+02:00", "2024-04-01T03:00:00+02:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := NextRuns(tt.expression, tt.timezone, from, tt.count)
+			if err != nil {
+				t.Fatalf("NextRuns: %v", err)
+			}
+			if len(runs) != len(tt.want) {
+				t.Fatalf("got %d runs, want %d", len(runs), len(tt.want))
+			}
+			for i, run := range runs {
+				if got := run.Format(time.RFC3339); got != tt.want[i] {
+					t.Errorf("run %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNextRunsCount(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, count := range []int{0, -1, maxScheduleRuns + 1} {
+		if _, err := NextRuns("@hourly", "", from, count); err == nil {
+			t.Errorf("NextRuns with count %d: expected an error", count)
+		}
+	}
+
+	runs, err := NextRuns("@hourly", "", from, maxScheduleRuns)
+	if err != nil {
+		t.Fatalf("NextRuns with count %d: %v", maxScheduleRuns, err)
+	}
+	if len(runs) != maxScheduleRuns {
+		t.Errorf("got %d runs, want %d", len(runs), maxScheduleRuns)
+	}
+}
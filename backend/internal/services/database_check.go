@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	_ "github.com/lib/pq"
+
+	"service-monitor/internal/models"
+)
+
+const (
+	defaultPostgresQuery = "SELECT 1"
+	defaultRedisCommand  = "PING"
+)
+
+// probePostgres connects to the database at the service URL (a postgres://
+// connection URL; Config.Username and Config.Password override its
+// credentials), runs Config.Query and compares the first column of the first
+// row with Config.ExpectedResult. Server role and replication lag are
+// recorded in the details.
+func probePostgres(ctx context.Context, service *models.Service) *CheckResult {
+	dsn, err := postgresDSN(service)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(service))
+	defer cancel()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return failedResult("down", 0, fmt.Sprintf("failed to open connection: %v", err))
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	query := strings.TrimSpace(service.Config.Query)
+	if query == "" {
+		query = defaultPostgresQuery
+	}
+
+	start := time.Now()
+	if err := db.PingContext(ctx); err != nil {
+		return failedResult("down", 0, fmt.Sprintf("failed to connect: %v", err))
+	}
+	value, err := firstValue(ctx, db, query)
+	responseTime := time.Since(start).Milliseconds()
+	if err != nil {
+		return failedResult("down", responseTime, fmt.Sprintf("probe query failed: %v", err))
+	}
+
+	details := models.CheckDetails{"result": value}
+	for key, info := range postgresRole(ctx, db) {
+		details[key] = info
+	}
+
+	result := &CheckResult{Status: "up", ResponseTime: responseTime, Details: details}
+	if service.Config.ExpectedResult != "" && value != service.Config.ExpectedResult {
+		result.Status = "down"
+		result.Error = fmt.Sprintf("query returned %q, expected %q", value, service.Config.ExpectedResult)
+	}
+
+	return result
+}
+
+func validatePostgres(service *models.Service) error {
+	_, err := postgresDSN(service)
+	return err
+}
+
+func postgresDSN(service *models.Service) (string, error) {
+	u, err := url.Parse(service.URL)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		return "", fmt.Errorf("invalid PostgreSQL URL: expected postgres://host:port/dbname")
+	}
+
+	if service.Config.Username != "" || service.Config.Password != "" {
+		username := service.Config.Username
+		if username == "" && u.User != nil {
+			username = u.User.Username()
+		}
+		u.User = url.UserPassword(username, service.Config.Password)
+	}
+
+	query := u.Query()
+	if query.Get("connect_timeout") == "" {
+		query.Set("connect_timeout", fmt.Sprint(int(checkTimeout(service).Seconds())))
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String(), nil
+}
+
+// firstValue runs query and returns the first column of the first row as a
+// string, whatever its type.
+func firstValue(ctx context.Context, db *sql.DB, query string) (string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	return string(values[0]), nil
+}
+
+// postgresRole reports whether the server is a primary or replica, its
+// version and, for replicas, the replay lag. Failures are ignored since the
+// probe itself already succeeded.
+func postgresRole(ctx context.Context, db *sql.DB) map[string]interface{} {
+	info := map[string]interface{}{}
+
+	var version string
+	if err := db.QueryRowContext(ctx, `SHOW server_version`).Scan(&version); err == nil {
+		info["server_version"] = version
+	}
+
+	var inRecovery bool
+	if err := db.QueryRowContext(ctx, `SELECT pg_is_in_recovery()`).Scan(&inRecovery); err != nil {
+		return info
+	}
+	if !inRecovery {
+		info["role"] = "primary"
+		return info
+	}
+
+	info["role"] = "replica"
+	var lag sql.NullFloat64
+	err := db.QueryRowContext(ctx, `SELECT EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())`).Scan(&lag)
+	if err == nil && lag.Valid {
+		info["replication_lag_seconds"] = lag.Float64
+	}
+
+	return info
+}
+
+// probeRedis connects to the Redis server at the service URL (a redis:// or
+// rediss:// URL; Config.Username and Config.Password override its
+// credentials), runs the configured command (PING by default) and compares
+// the reply with Config.ExpectedResult. Replication role is recorded in the details.
+func probeRedis(ctx context.Context, service *models.Service) *CheckResult {
+	options, err := redis.ParseURL(service.URL)
+	if err != nil {
+		return failedResult("down", 0, fmt.Sprintf("invalid Redis URL: %v", err))
+	}
+	if service.Config.Username != "" {
+		options.Username = service.Config.Username
+	}
+	if service.Config.Password != "" {
+		options.Password = service.Config.Password
+	}
+	timeout := checkTimeout(service)
+	options.DialTimeout = timeout
+	options.ReadTimeout = timeout
+	options.WriteTimeout = timeout
+	options.PoolSize = 1
+	options.MaxRetries = -1
+
+	client := redis.NewClient(options)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fields := redisCommand(service.Config)
+	args := make([]interface{}, len(fields))
+	for i, field := range fields {
+		args[i] = field
+	}
+
+	start := time.Now()
+	reply, err := client.Do(ctx, args...).Result()
+	responseTime := time.Since(start).Milliseconds()
+	if err != nil && err != redis.Nil {
+		return failedResult("down", responseTime, fmt.Sprintf("%s failed: %v", fields[0], err))
+	}
+
+	value := fmt.Sprint(reply)
+	if err == redis.Nil {
+		value = ""
+	}
+
+	details := models.CheckDetails{"result": value}
+	if replication, err := client.Info(ctx, "replication").Result(); err == nil {
+		for key, info := range parseRedisInfo(replication) {
+			details[key] = info
+		}
+	}
+
+	result := &CheckResult{Status: "up", ResponseTime: responseTime, Details: details}
+	if service.Config.ExpectedResult != "" && value != service.Config.ExpectedResult {
+		result.Status = "down"
+		result.Error = fmt.Sprintf("%s returned %q, expected %q", fields[0], value, service.Config.ExpectedResult)
+	}
+
+	return result
+}
+
+func validateRedis(service *models.Service) error {
+	if _, err := redis.ParseURL(service.URL); err != nil {
+		return fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if len(service.Config.Command) > 0 {
+		if strings.TrimSpace(service.Config.Query) != "" {
+			return fmt.Errorf("set either command or query, not both")
+		}
+		if strings.TrimSpace(service.Config.Command[0]) == "" {
+			return fmt.Errorf("command must start with a command name")
+		}
+	}
+	return nil
+}
+
+// redisCommand returns the command to run: Config.Command, whose arguments
+// may contain spaces, or else Config.Query split on whitespace, or PING.
+func redisCommand(cfg models.ServiceConfig) []string {
+	if len(cfg.Command) > 0 {
+		return cfg.Command
+	}
+	if fields := strings.Fields(cfg.Query); len(fields) > 0 {
+		return fields
+	}
+	return []string{defaultRedisCommand}
+}
+
+// parseRedisInfo extracts the replication fields worth recording from the
+// output of INFO replication.
+func parseRedisInfo(info string) map[string]string {
+	wanted := map[string]bool{
+		"role":                       true,
+		"connected_slaves":           true,
+		"master_host":                true,
+		"master_link_status":         true,
+		"master_last_io_seconds_ago": true,
+	}
+
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && wanted[key] {
+			fields[key] = value
+		}
+	}
+
+	return fields
+}
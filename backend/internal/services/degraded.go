@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"service-monitor/internal/models"
+)
+
+const defaultP95Window = 20
+
+// applyLatencyThresholds marks an up result degraded when its response time
+// exceeds Config.DegradedResponseTime, or when the p95 over the last
+// Config.P95Window checks including this one exceeds Config.DegradedP95.
+// Unsaved services, with ID 0, have no history and the p95 covers this
+// check alone.
+func (s *HealthCheckService) applyLatencyThresholds(ctx context.Context, service *models.Service, result *CheckResult) error {
+	cfg := service.Config
+	if result.Status != "up" || (cfg.DegradedResponseTime <= 0 && cfg.DegradedP95 <= 0) {
+		return nil
+	}
+
+	if cfg.DegradedResponseTime > 0 && result.ResponseTime > int64(cfg.DegradedResponseTime) {
+		result.Status = "degraded"
+		result.Error = fmt.Sprintf("response time %dms exceeds %dms", result.ResponseTime, cfg.DegradedResponseTime)
+		return nil
+	}
+
+	if cfg.DegradedP95 <= 0 {
+		return nil
+	}
+
+	window := cfg.P95Window
+	if window <= 0 {
+		window = defaultP95Window
+	}
+	var times []int64
+	if service.ID != 0 {
+		var err error
+		times, err = s.recentResponseTimes(ctx, service.ID, window-1)
+		if err != nil {
+			return err
+		}
+	}
+	p95 := percentile(append(times, result.ResponseTime), 0.95)
+
+	if result.Details == nil {
+		result.Details = models.CheckDetails{}
+	}
+	result.Details["p95_ms"] = p95
+	if p95 > int64(cfg.DegradedP95) {
+		result.Status = "degraded"
+		result.Error = fmt.Sprintf("p95 response time %dms over the last %d checks exceeds %dms", p95, window, cfg.DegradedP95)
+	}
+
+	return nil
+}
+
+// recentResponseTimes returns the response times of the service's latest
+// successful checks.
+func (s *HealthCheckService) recentResponseTimes(ctx context.Context, serviceID int64, limit int) ([]int64, error) {
+	query := `
+		SELECT response_time
+		FROM health_checks
+		WHERE service_id = $1 AND status IN ('up', 'degraded')
+		ORDER BY checked_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, serviceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response times: %w", err)
+	}
+	defer rows.Close()
+
+	var times []int64
+	for rows.Next() {
+		var t int64
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("failed to scan response time: %w", err)
+		}
+		times = append(times, t)
+	}
+
+	return times, rows.Err()
+}
+
+// percentile returns the nearest-rank percentile p (0-1] of values.
+func percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// updateDegraded raises a degraded alert while checks are degraded and
+// resolves it once they are not, unless the service is already alerting as
+// down.
+func (s *HealthCheckService) updateDegraded(ctx context.Context, service *models.Service, result *CheckResult) error {
+	if s.alertService == nil {
+		return nil
+	}
+
+	active, err := s.alertService.GetActiveAlert(ctx, service.ID, models.SeverityDegraded)
+	if err != nil {
+		return err
+	}
+
+	if result.Status != "degraded" {
+		if active != nil {
+			return s.alertService.ResolveServiceAlerts(ctx, service.ID, models.SeverityDegraded)
+		}
+		return nil
+	}
+
+	if active != nil || result.inMaintenance || alertPolicy(service.Config, models.SeverityDegraded) == models.AlertPolicyNone {
+		return nil
+	}
+	critical, err := s.alertService.GetActiveAlert(ctx, service.ID, models.SeverityCritical)
+	if err != nil || critical != nil {
+		return err
+	}
+
+	_, err = s.alertService.CreateAlertWithSeverity(ctx, service.ID, models.SeverityDegraded, result.Error)
+	return err
+}
+
+// alertPolicy returns how alerts of a severity are raised for a service.
+// Critical alerts page the escalation chain unless configured otherwise;
+// degraded alerts and warnings, such as an expiring certificate, are only
+// recorded.
+func alertPolicy(cfg models.ServiceConfig, severity string) string {
+	switch severity {
+	case models.SeverityCritical:
+		if cfg.DownAlert != "" {
+			return cfg.DownAlert
+		}
+		return models.AlertPolicyPage
+	case models.SeverityDegraded:
+		if cfg.DegradedAlert != "" {
+			return cfg.DegradedAlert
+		}
+	case models.SeverityWarning:
+		if cfg.WarningAlert != "" {
+			return cfg.WarningAlert
+		}
+	}
+	return models.AlertPolicyRecord
+}
+
+// notifies reports whether alerts raised under policy notify the escalation
+// chain.
+func notifies(policy string) bool {
+	return policy == models.AlertPolicySMS || policy == models.AlertPolicyPage
+}
+
+func validateAlerting(service *models.Service) error {
+	cfg := service.Config
+	if cfg.DegradedResponseTime < 0 || cfg.DegradedP95 < 0 || cfg.P95Window < 0 {
+		return fmt.Errorf("latency thresholds must not be negative")
+	}
+
+	for _, policy := range []string{cfg.DegradedAlert, cfg.DownAlert, cfg.WarningAlert} {
+		switch policy {
+		case "", models.AlertPolicyNone, models.AlertPolicyRecord, models.AlertPolicySMS, models.AlertPolicyPage:
+		default:
+			return fmt.Errorf("unknown alert policy %q", policy)
+		}
+	}
+
+	return nil
+}
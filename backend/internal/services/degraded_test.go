@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"service-monitor/internal/models"
+)
+
+func TestApplyLatencyThresholdsUnsaved(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          models.ServiceConfig
+		status       string
+		responseTime int64
+		want         string
+	}{
+		{"no thresholds", models.ServiceConfig{}, "up", 900, "up"},
+		{"under response time", models.ServiceConfig{DegradedResponseTime: 500}, "up", 400, "up"},
+		{"over response time", models.ServiceConfig{DegradedResponseTime: 500}, "up", 600, "degraded"},
+		{"down stays down", models.ServiceConfig{DegradedResponseTime: 500}, "down", 600, "down"},
+		{"under p95", models.ServiceConfig{DegradedP95: 500}, "up", 400, "up"},
+		{"over p95", models.ServiceConfig{DegradedP95: 500}, "up", 600, "degraded"},
+	}
+
+	// Without a database, any history lookup for the unsaved service would
+	// panic
+	s := &HealthCheckService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &models.Service{Config: tt.cfg}
+			result := &CheckResult{Status: tt.status, ResponseTime: tt.responseTime}
+			if err := s.applyLatencyThresholds(context.Background(), service, result); err != nil {
+				t.Fatalf("applyLatencyThresholds: %v", err)
+			}
+			if result.Status != tt.want {
+				t.Errorf("status = %q, want %q", result.Status, tt.want)
+			}
+		})
+	}
+}
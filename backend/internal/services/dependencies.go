@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/models"
+)
+
+// ValidateDependencies checks that every dependency exists and that making
+// serviceID depend on them would not create a cycle. serviceID is 0 for a
+// service that has not been created yet.
+func (s *ServiceService) ValidateDependencies(ctx context.Context, serviceID int64, dependsOn []int64) error {
+	if len(dependsOn) == 0 {
+		return nil
+	}
+
+	unique := make(map[int64]bool, len(dependsOn))
+	for _, id := range dependsOn {
+		if id == serviceID {
+			return fmt.Errorf("a service cannot depend on itself")
+		}
+		unique[id] = true
+	}
+
+	var found int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM services WHERE id = ANY($1)`, pq.Array(dependsOn)).Scan(&found)
+	if err != nil {
+		return fmt.Errorf("failed to check dependencies: %w", err)
+	}
+	if found != len(unique) {
+		return fmt.Errorf("dependency on a service that does not exist")
+	}
+
+	if serviceID == 0 {
+		return nil
+	}
+
+	// The service's current dependencies are about to be replaced, so they
+	// are left out of the walk
+	query := `
+		WITH RECURSIVE upstream(id) AS (
+			SELECT unnest($2::bigint[])
+			UNION
+			SELECT d.depends_on_id::bigint
+			FROM service_dependencies d
+			JOIN upstream u ON d.service_id = u.id
+			WHERE d.service_id <> $1
+		)
+		SELECT EXISTS (SELECT 1 FROM upstream WHERE id = $1)
+	`
+
+	var cycle bool
+	if err := s.db.QueryRowContext(ctx, query, serviceID, pq.Array(dependsOn)).Scan(&cycle); err != nil {
+		return fmt.Errorf("failed to check dependencies: %w", err)
+	}
+	if cycle {
+		return fmt.Errorf("dependencies would create a cycle")
+	}
+
+	return nil
+}
+
+// setDependencies replaces the service's dependencies.
+func (s *ServiceService) setDependencies(ctx context.Context, serviceID int64, dependsOn []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM service_dependencies WHERE service_id = $1`, serviceID); err != nil {
+		return fmt.Errorf("failed to update dependencies: %w", err)
+	}
+
+	query := `
+		INSERT INTO service_dependencies (service_id, depends_on_id)
+		SELECT $1, unnest($2::bigint[])
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, serviceID, pq.Array(dependsOn)); err != nil {
+		return fmt.Errorf("failed to update dependencies: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update dependencies: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ServiceService) getDependencies(ctx context.Context, serviceID int64) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT depends_on_id FROM service_dependencies WHERE service_id = $1 ORDER BY depends_on_id
+	`, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetDependencyGraph returns every service with its state and all
+// dependencies between services.
+func (s *ServiceService) GetDependencyGraph(ctx context.Context) (*models.DependencyGraph, error) {
+	graph := &models.DependencyGraph{
+		Nodes: []models.DependencyNode{},
+		Edges: []models.DependencyEdge{},
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.id, s.name, s.type, COALESCE(st.state, ''), st.impacted_by
+		FROM services s
+		LEFT JOIN service_states st ON st.service_id = s.id
+		ORDER BY s.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependency graph: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var node models.DependencyNode
+		var impactedBy sql.NullInt64
+		if err := rows.Scan(&node.ID, &node.Name, &node.Type, &node.State, &impactedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan service: %w", err)
+		}
+		if impactedBy.Valid {
+			node.ImpactedBy = &impactedBy.Int64
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating services: %w", err)
+	}
+
+	edges, err := s.db.QueryContext(ctx, `
+		SELECT service_id, depends_on_id FROM service_dependencies ORDER BY service_id, depends_on_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependency graph: %w", err)
+	}
+	defer edges.Close()
+
+	for edges.Next() {
+		var edge models.DependencyEdge
+		if err := edges.Scan(&edge.ServiceID, &edge.DependsOnID); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		graph.Edges = append(graph.Edges, edge)
+	}
+	if err := edges.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dependencies: %w", err)
+	}
+
+	return graph, nil
+}
+
+// downUpstream returns a transitive upstream dependency of the service that
+// is down, preferring one that is not itself impacted (the root cause), or
+// nil if none is down.
+func (s *HealthCheckService) downUpstream(ctx context.Context, serviceID int64) (*int64, error) {
+	query := `
+		WITH RECURSIVE upstream(id) AS (
+			SELECT depends_on_id FROM service_dependencies WHERE service_id = $1
+			UNION
+			SELECT d.depends_on_id
+			FROM service_dependencies d
+			JOIN upstream u ON d.service_id = u.id
+		)
+		SELECT u.id
+		FROM upstream u
+		JOIN service_states st ON st.service_id = u.id
+		WHERE st.state = 'down' AND u.id <> $1
+		ORDER BY st.impacted_by IS NULL DESC, st.changed_at
+		LIMIT 1
+	`
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, query, serviceID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check upstream dependencies: %w", err)
+	}
+
+	return &id, nil
+}
+
+// suppressDependents marks the transitive dependents of a service that just
+// went down as impacted by it, if they are already down, and suppresses
+// their active alerts so escalation stops.
+func (s *HealthCheckService) suppressDependents(ctx context.Context, serviceID int64) error {
+	query := `
+		WITH RECURSIVE downstream(id) AS (
+			SELECT service_id FROM service_dependencies WHERE depends_on_id = $1
+			UNION
+			SELECT d.service_id
+			FROM service_dependencies d
+			JOIN downstream ds ON d.depends_on_id = ds.id
+		),
+		impacted AS (
+			UPDATE service_states
+			SET impacted_by = $1
+			WHERE service_id IN (SELECT id FROM downstream WHERE id <> $1)
+			  AND state IN ('down', 'recovering') AND impacted_by IS NULL
+			RETURNING service_id
+		)
+		UPDATE alerts
+		SET status = 'suppressed', resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE service_id IN (SELECT service_id FROM impacted)
+		  AND status = 'active' AND severity IN ('critical', 'degraded')
+	`
+
+	if _, err := s.db.ExecContext(ctx, query, serviceID); err != nil {
+		return fmt.Errorf("failed to suppress dependent alerts: %w", err)
+	}
+
+	return nil
+}
+
+// releaseDependents clears the impact of a recovered service on its
+// dependents. Dependents that are still down are reconsidered as if they
+// had just gone down, so they alert unless another upstream is down.
+func (s *HealthCheckService) releaseDependents(ctx context.Context, serviceID int64) error {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE service_states
+		SET impacted_by = NULL
+		WHERE impacted_by = $1
+		RETURNING service_id, state
+	`, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to release dependents: %w", err)
+	}
+
+	var stillDown []int64
+	for rows.Next() {
+		var id int64
+		var state string
+		if err := rows.Scan(&id, &state); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan dependent: %w", err)
+		}
+		if state == models.StateDown {
+			stillDown = append(stillDown, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to release dependents: %w", err)
+	}
+
+	serviceService := NewServiceService(s.db)
+	for _, id := range stillDown {
+		dependent, err := serviceService.GetService(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.raiseDownAlert(ctx, dependent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *HealthCheckService) setImpactedBy(ctx context.Context, serviceID int64, upstreamID *int64) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE service_states SET impacted_by = $2 WHERE service_id = $1`, serviceID, upstreamID)
+	if err != nil {
+		return fmt.Errorf("failed to update impacted service: %w", err)
+	}
+	return nil
+}
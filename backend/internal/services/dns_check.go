@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// probeDNS resolves the service URL's name for Config.RecordType (A by
+// default) against Config.Resolver, or the system resolver when unset. Every
+// value in Config.ExpectedValues must be among the answers. Config.Assertions
+// are evaluated with the answers, one per line, as the body.
+func probeDNS(ctx context.Context, service *models.Service) *CheckResult {
+	name, err := dnsName(service.URL)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	recordType := strings.ToUpper(service.Config.RecordType)
+	if recordType == "" {
+		recordType = "A"
+	}
+
+	resolver := newResolver(service.Config.Resolver)
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(service))
+	defer cancel()
+
+	start := time.Now()
+	answers, err := lookup(ctx, resolver, recordType, name)
+	responseTime := time.Since(start).Milliseconds()
+	if err != nil {
+		return failedResult("down", responseTime, err.Error())
+	}
+
+	details := models.CheckDetails{
+		"name":        name,
+		"record_type": recordType,
+		"answers":     answers,
+	}
+	if service.Config.Resolver != "" {
+		details["resolver"] = service.Config.Resolver
+	}
+	result := &CheckResult{Status: "up", ResponseTime: responseTime, Details: details}
+
+	var failures []string
+	if missing := missingValues(answers, service.Config.ExpectedValues); len(missing) > 0 {
+		failures = append(failures, fmt.Sprintf("expected %s not in answers [%s]",
+			strings.Join(missing, ", "), strings.Join(answers, ", ")))
+	}
+
+	if len(service.Config.Assertions) > 0 {
+		results, assertionFailures := evaluateAssertions(service.Config.Assertions, &httpResponse{
+			Body:         []byte(strings.Join(answers, "\n")),
+			ResponseTime: responseTime,
+		})
+		details["assertions"] = results
+		failures = append(failures, assertionFailures...)
+	}
+
+	if len(failures) > 0 {
+		result.Status = "down"
+		result.Error = strings.Join(failures, "; ")
+	}
+
+	return result
+}
+
+func validateDNS(service *models.Service) error {
+	if _, err := dnsName(service.URL); err != nil {
+		return err
+	}
+
+	switch strings.ToUpper(service.Config.RecordType) {
+	case "", "A", "AAAA", "CNAME", "MX", "TXT", "SRV":
+	default:
+		return fmt.Errorf("unsupported DNS record type %q", service.Config.RecordType)
+	}
+
+	return validateAssertions(service.Config.Assertions)
+}
+
+// lookup resolves name for recordType and returns the answers normalised to
+// strings without trailing dots, sorted for stable comparison.
+func lookup(ctx context.Context, resolver *net.Resolver, recordType, name string) ([]string, error) {
+	var answers []string
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range records {
+			answers = append(answers, mx.Host)
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, records...)
+	case "SRV":
+		_, records, err := resolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range records {
+			answers = append(answers, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DNS record type %q", recordType)
+	}
+
+	for i, answer := range answers {
+		answers[i] = strings.TrimSuffix(answer, ".")
+	}
+	sort.Strings(answers)
+
+	return answers, nil
+}
+
+// newResolver returns a resolver that sends every query to address, or the
+// system resolver when address is empty.
+func newResolver(address string) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// missingValues returns the expected values not present in answers. Values
+// are compared case-insensitively and without trailing dots.
+func missingValues(answers, expected []string) []string {
+	present := make(map[string]bool, len(answers))
+	for _, answer := range answers {
+		present[strings.ToLower(answer)] = true
+	}
+
+	var missing []string
+	for _, value := range expected {
+		if !present[strings.ToLower(strings.TrimSuffix(value, "."))] {
+			missing = append(missing, value)
+		}
+	}
+
+	return missing
+}
+
+// dnsName extracts the name to resolve from a service URL. Bare names are
+// used as is; URL forms such as "dns://example.com" use their hostname.
+func dnsName(rawURL string) (string, error) {
+	name := strings.TrimSpace(rawURL)
+	if strings.Contains(name, "://") {
+		u, err := url.Parse(name)
+		if err != nil {
+			return "", fmt.Errorf("invalid service URL: %w", err)
+		}
+		name = u.Hostname()
+	}
+	if name == "" {
+		return "", fmt.Errorf("invalid DNS name %q", rawURL)
+	}
+
+	return name, nil
+}
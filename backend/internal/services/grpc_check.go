@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"service-monitor/internal/models"
+)
+
+// probeGRPC calls grpc.health.v1.Health/Check for Config.GRPCService (the
+// whole server when empty). Only SERVING counts as up. The service URL is
+// host:port, grpc://host:port or grpcs://host:port; TLS is used for grpcs://
+// or when Config.GRPCTLS is set. Config.Headers are sent as metadata.
+func probeGRPC(ctx context.Context, service *models.Service) *CheckResult {
+	target, useTLS, err := grpcTarget(service.URL)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+	useTLS = useTLS || service.Config.GRPCTLS
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		tlsConfig, err := newTLSConfig(service.Config)
+		if err != nil {
+			return failedResult("down", 0, err.Error())
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return failedResult("down", 0, fmt.Sprintf("invalid gRPC target: %v", err))
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(service))
+	defer cancel()
+
+	if len(service.Config.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(service.Config.Headers))
+	}
+
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: service.Config.GRPCService,
+	})
+	responseTime := time.Since(start).Milliseconds()
+	if err != nil {
+		return failedResult("down", responseTime, fmt.Sprintf("health check failed: %v", err))
+	}
+
+	status := resp.GetStatus()
+	result := &CheckResult{
+		Status:       "up",
+		ResponseTime: responseTime,
+		Details:      models.CheckDetails{"serving_status": status.String()},
+	}
+	if status != healthpb.HealthCheckResponse_SERVING {
+		result.Status = "down"
+		result.Error = fmt.Sprintf("gRPC health status %s", status)
+	}
+
+	return result
+}
+
+func validateGRPC(service *models.Service) error {
+	if _, _, err := grpcTarget(service.URL); err != nil {
+		return err
+	}
+	_, err := newTLSConfig(service.Config)
+	return err
+}
+
+// grpcTarget turns a service URL into a dial target and reports whether the
+// scheme asks for TLS.
+func grpcTarget(rawURL string) (string, bool, error) {
+	address := strings.TrimSpace(rawURL)
+	useTLS := false
+
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid service URL: %w", err)
+		}
+		switch u.Scheme {
+		case "grpc":
+		case "grpcs":
+			useTLS = true
+		default:
+			return "", false, fmt.Errorf("unsupported gRPC URL scheme %q", u.Scheme)
+		}
+		address = u.Host
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", false, fmt.Errorf("invalid gRPC address %q: %w", rawURL, err)
+	}
+
+	return address, useTLS, nil
+}
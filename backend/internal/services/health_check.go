@@ -4,71 +4,235 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"net/http"
 	"time"
 	"service-monitor/internal/models"
 )
 
 type HealthCheckService struct {
-	db *sql.DB
+	db           *sql.DB
+	alertService *AlertService
+	maintenance  *MaintenanceService
 }
 
-func NewHealthCheckService(db *sql.DB) *HealthCheckService {
-	return &HealthCheckService{db: db}
+func NewHealthCheckService(db *sql.DB, alertService *AlertService) *HealthCheckService {
+	return &HealthCheckService{
+		db:           db,
+		alertService: alertService,
+		maintenance:  NewMaintenanceService(db),
+	}
+}
+
+const (
+	defaultCheckTimeout = 10 * time.Second
+	retryDelay          = time.Second
+)
+
+// CheckResult is the outcome of a single probe, before it is recorded.
+type CheckResult struct {
+	Status       string // up, degraded, down or unknown
+	ResponseTime int64  // in milliseconds
+	Error        string
+	Details      models.CheckDetails
+	Timing       *models.CheckTiming // phase breakdown, HTTP checks only
+
+	// Warning flags a problem that does not affect the status, such as a
+	// certificate close to expiry, and raises a warning alert.
+	Warning string
+
+	// inMaintenance is set when the result is recorded during a maintenance
+	// window, in which case it raises no alerts.
+	inMaintenance bool
 }
 
+// healthy reports whether the result counts as a success for the service
+// state machine. Degraded services are slow or lossy but still reachable.
+func (r *CheckResult) healthy() bool {
+	return r.Status == "up" || r.Status == "degraded"
+}
+
+func failedResult(status string, responseTime int64, errorMsg string) *CheckResult {
+	return &CheckResult{Status: status, ResponseTime: responseTime, Error: errorMsg}
+}
+
+// CheckService probes the service, retrying up to Config.RetryCount times on
+// failure, records the result and advances the service's state machine. If
+// the check is recorded but updating state or alerts fails, the recorded
+// check is returned along with the error.
 func (s *HealthCheckService) CheckService(ctx context.Context, service *models.Service) (*models.HealthCheck, error) {
-	start := time.Now()
+	result, err := probeWithRetries(ctx, service)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	return s.RecordResult(ctx, service, result)
+}
+
+// TestService probes a service definition that may not be saved yet and
+// returns the result as it would be recorded, without storing anything or
+// touching the service's state and alerts. Heartbeat services have nothing
+// to probe until they receive pings, so testing one is not meaningful. Any
+// ID in the definition is ignored, so that latency thresholds are not
+// evaluated against another service's history.
+func (s *HealthCheckService) TestService(ctx context.Context, service *models.Service) (*models.HealthCheck, error) {
+	unsaved := *service
+	unsaved.ID = 0
+
+	result, err := probeWithRetries(ctx, &unsaved)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyLatencyThresholds(ctx, &unsaved, result); err != nil {
+		return nil, err
 	}
 
-	// Make request
-	resp, err := client.Get(service.URL)
+	return &models.HealthCheck{
+		Status:       result.Status,
+		ResponseTime: result.ResponseTime,
+		Error:        result.Error,
+		Details:      result.Details,
+		Timing:       result.Timing,
+		Warning:      result.Warning,
+		CheckedAt:    time.Now(),
+	}, nil
+}
+
+// probeWithRetries probes the service, retrying up to Config.RetryCount
+// times on failure.
+func probeWithRetries(ctx context.Context, service *models.Service) (*CheckResult, error) {
+	result := probe(ctx, service)
+
+	attempts := 1
+	for attempts <= service.Config.RetryCount && !result.healthy() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryDelay):
+		}
+
+		result = probe(ctx, service)
+		attempts++
+	}
+
+	if attempts > 1 {
+		if result.Details == nil {
+			result.Details = models.CheckDetails{}
+		}
+		result.Details["attempts"] = attempts
+	}
+
+	return result, nil
+}
+
+// RecordResult applies the service's latency thresholds to a check result,
+// stores it flagged with whether the service is in maintenance and then
+// advances the service's state and raises or resolves its alerts. Probed
+// results come through CheckService; heartbeat pings are recorded directly.
+// If the check is stored but a later step fails, the stored check is
+// returned along with the error.
+func (s *HealthCheckService) RecordResult(ctx context.Context, service *models.Service, result *CheckResult) (*models.HealthCheck, error) {
+	if err := s.applyLatencyThresholds(ctx, service, result); err != nil {
+		return nil, err
+	}
+
+	window, err := s.maintenance.ActiveWindow(ctx, service.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	result.inMaintenance = window != nil
+
+	check, err := s.recordHealthCheck(ctx, service.ID, result)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.updateState(ctx, service, check); err != nil {
+		return check, err
+	}
+
+	if err := s.updateWarning(ctx, service, result); err != nil {
+		return check, err
+	}
+
+	if err := s.updateDegraded(ctx, service, result); err != nil {
+		return check, err
+	}
+
+	return check, nil
+}
+
+// updateWarning raises a warning alert when a check reports one and resolves
+// it once a successful check no longer does.
+func (s *HealthCheckService) updateWarning(ctx context.Context, service *models.Service, result *CheckResult) error {
+	if s.alertService == nil {
+		return nil
+	}
+
+	active, err := s.alertService.GetActiveAlert(ctx, service.ID, models.SeverityWarning)
 	if err != nil {
-		return s.recordHealthCheck(ctx, service.ID, "down", 0, err.Error())
+		return err
 	}
-	defer resp.Body.Close()
 
-	// Calculate response time
-	responseTime := time.Since(start).Milliseconds()
+	if result.Warning == "" {
+		if active != nil && result.healthy() {
+			return s.alertService.ResolveServiceAlerts(ctx, service.ID, models.SeverityWarning)
+		}
+		return nil
+	}
 
-	// Check if status code is successful
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return s.recordHealthCheck(ctx, service.ID, "up", responseTime, "")
+	if active != nil || result.inMaintenance || alertPolicy(service.Config, models.SeverityWarning) == models.AlertPolicyNone {
+		return nil
 	}
+	_, err = s.alertService.CreateAlertWithSeverity(ctx, service.ID, models.SeverityWarning, result.Warning)
+	return err
+}
+
+func probe(ctx context.Context, service *models.Service) *CheckResult {
+	checker, ok := LookupChecker(service.Type)
+	if !ok {
+		return failedResult("unknown", 0, fmt.Sprintf("no checker registered for service type %q", service.Type))
+	}
+	return checker.Check(ctx, service)
+}
 
-	return s.recordHealthCheck(ctx, service.ID, "down", responseTime, fmt.Sprintf("HTTP %d", resp.StatusCode))
+// checkTimeout returns the per-check timeout from the service config
+// (in seconds), or the default when none is configured.
+func checkTimeout(service *models.Service) time.Duration {
+	if service.Config.Timeout > 0 {
+		return time.Duration(service.Config.Timeout) * time.Second
+	}
+	return defaultCheckTimeout
 }
 
-func (s *HealthCheckService) recordHealthCheck(ctx context.Context, serviceID int64, status string, responseTime int64, errorMsg string) (*models.HealthCheck, error) {
+func (s *HealthCheckService) recordHealthCheck(ctx context.Context, serviceID int64, result *CheckResult) (*models.HealthCheck, error) {
 	query := `
-		INSERT INTO health_checks (service_id, status, response_time, error)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, service_id, status, response_time, error, checked_at
+		INSERT INTO health_checks (service_id, status, response_time, error, details, timing, in_maintenance)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, service_id, status, response_time, error, details, timing, in_maintenance, checked_at
 	`
 
 	var check models.HealthCheck
-	err := s.db.QueryRowContext(ctx, query, serviceID, status, responseTime, errorMsg).Scan(
+	err := s.db.QueryRowContext(ctx, query, serviceID, result.Status, result.ResponseTime, result.Error, result.Details, result.Timing, result.inMaintenance).Scan(
 		&check.ID,
 		&check.ServiceID,
 		&check.Status,
 		&check.ResponseTime,
 		&check.Error,
+		&check.Details,
+		&check.Timing,
+		&check.InMaintenance,
 		&check.CheckedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to record health check: %w", err)
 	}
+	check.Warning = result.Warning
 
 	return &check, nil
 }
 
 func (s *HealthCheckService) GetLatestHealthCheck(ctx context.Context, serviceID int64) (*models.HealthCheck, error) {
 	query := `
-		SELECT id, service_id, status, response_time, error, checked_at
+		SELECT id, service_id, status, response_time, error, details, timing, in_maintenance, checked_at
 		FROM health_checks
 		WHERE service_id = $1
 		ORDER BY checked_at DESC
@@ -82,6 +246,9 @@ func (s *HealthCheckService) GetLatestHealthCheck(ctx context.Context, serviceID
 		&check.Status,
 		&check.ResponseTime,
 		&check.Error,
+		&check.Details,
+		&check.Timing,
+		&check.InMaintenance,
 		&check.CheckedAt,
 	)
 	if err != nil {
@@ -89,4 +256,4 @@ func (s *HealthCheckService) GetLatestHealthCheck(ctx context.Context, serviceID
 	}
 
 	return &check, nil
-} 
\ No newline at end of file
+}
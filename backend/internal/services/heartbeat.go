@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+const (
+	defaultHeartbeatGrace  = time.Minute
+	heartbeatCheckInterval = time.Minute
+)
+
+// Heartbeat ping events.
+const (
+	HeartbeatSuccess = "success"
+	HeartbeatStart   = "start"
+	HeartbeatFail    = "fail"
+)
+
+// HeartbeatService handles push monitors: jobs ping a secret URL when they
+// start and finish, and the service is considered down once a ping is more
+// than Config.Grace seconds later than Config.Period. It is also the Checker
+// for heartbeat services, so the scheduler's regular checks are what notice
+// missed pings.
+type HeartbeatService struct {
+	db                 *sql.DB
+	healthCheckService *HealthCheckService
+}
+
+func NewHeartbeatService(db *sql.DB, healthCheckService *HealthCheckService) *HeartbeatService {
+	return &HeartbeatService{
+		db:                 db,
+		healthCheckService: healthCheckService,
+	}
+}
+
+// EnsureToken returns the service's ping token, generating one the first
+// time it is called.
+func (s *HeartbeatService) EnsureToken(ctx context.Context, serviceID int64) (string, error) {
+	token, err := newPingToken()
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+		INSERT INTO heartbeats (service_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (service_id) DO NOTHING
+	`
+	if _, err := s.db.ExecContext(ctx, query, serviceID, token); err != nil {
+		return "", fmt.Errorf("failed to create ping token: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, `SELECT token FROM heartbeats WHERE service_id = $1`, serviceID).Scan(&token)
+	if err != nil {
+		return "", fmt.Errorf("failed to get ping token: %w", err)
+	}
+
+	return token, nil
+}
+
+// Ping records a ping from a monitored job. Start pings only mark the job as
+// running; success and fail pings are recorded in the service's health
+// history, with the job's duration when a start ping preceded them. exitCode,
+// when given, is stored with the ping.
+func (s *HeartbeatService) Ping(ctx context.Context, token, event string, exitCode *int) (*models.HealthCheck, error) {
+	query := `
+		SELECT s.id, s.name, s.type, s.url, s.config, s.created_at, s.updated_at, h.last_start_at
+		FROM heartbeats h
+		JOIN services s ON s.id = h.service_id
+		WHERE h.token = $1 AND LOWER(s.type) = $2
+	`
+
+	var service models.Service
+	var lastStart sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, token, string(models.ServiceTypeHeartbeat)).Scan(
+		&service.ID,
+		&service.Name,
+		&service.Type,
+		&service.URL,
+		&service.Config,
+		&service.CreatedAt,
+		&service.UpdatedAt,
+		&lastStart,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("heartbeat not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heartbeat: %w", err)
+	}
+
+	now := time.Now()
+
+	if event == HeartbeatStart {
+		_, err := s.db.ExecContext(ctx, `UPDATE heartbeats SET last_start_at = $2 WHERE service_id = $1`, service.ID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record heartbeat start: %w", err)
+		}
+		return nil, nil
+	}
+
+	result := &CheckResult{
+		Status:  "up",
+		Details: models.CheckDetails{"event": event},
+	}
+	if exitCode != nil {
+		result.Details["exit_code"] = *exitCode
+	}
+	if event == HeartbeatFail {
+		result.Status = "down"
+		result.Error = "job reported failure"
+		if exitCode != nil {
+			result.Error = fmt.Sprintf("job exited with code %d", *exitCode)
+		}
+	}
+	if lastStart.Valid {
+		result.ResponseTime = now.Sub(lastStart.Time).Milliseconds()
+		result.Details["started_at"] = lastStart.Time
+		result.Details["duration_ms"] = result.ResponseTime
+	}
+
+	update := `
+		UPDATE heartbeats
+		SET last_ping_at = $2, last_ping_status = $3, last_error = $4, last_start_at = NULL
+		WHERE service_id = $1
+	`
+	if _, err := s.db.ExecContext(ctx, update, service.ID, now, result.Status, result.Error); err != nil {
+		return nil, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	return s.healthCheckService.RecordResult(ctx, &service, result)
+}
+
+// Check reports the heartbeat down when the last ping, or the monitor's
+// creation if it was never pinged, is more than a period plus grace ago, or
+// when the last ping reported a failure.
+func (s *HeartbeatService) Check(ctx context.Context, service *models.Service) *CheckResult {
+	query := `
+		SELECT last_ping_at, last_ping_status, last_error, last_start_at, created_at
+		FROM heartbeats
+		WHERE service_id = $1
+	`
+
+	var (
+		lastPing   sql.NullTime
+		lastStatus sql.NullString
+		lastError  string
+		lastStart  sql.NullTime
+		createdAt  time.Time
+	)
+	err := s.db.QueryRowContext(ctx, query, service.ID).Scan(&lastPing, &lastStatus, &lastError, &lastStart, &createdAt)
+	if err == sql.ErrNoRows {
+		return failedResult("unknown", 0, "heartbeat has no ping token")
+	}
+	if err != nil {
+		return failedResult("unknown", 0, fmt.Sprintf("failed to get heartbeat: %v", err))
+	}
+
+	reference := createdAt
+	if lastPing.Valid {
+		reference = lastPing.Time
+	}
+	due := reference.Add(time.Duration(service.Config.Period) * time.Second)
+
+	details := models.CheckDetails{
+		"due_at":  due,
+		"running": lastStart.Valid,
+	}
+	if lastPing.Valid {
+		details["last_ping_at"] = lastPing.Time
+	}
+
+	var result *CheckResult
+	switch {
+	case time.Now().After(due.Add(heartbeatGrace(service))):
+		msg := "no ping received yet"
+		if lastPing.Valid {
+			msg = fmt.Sprintf("no ping received since %s", lastPing.Time.Format(time.RFC3339))
+		}
+		result = failedResult("down", 0, msg)
+	case lastStatus.String == "down":
+		result = failedResult("down", 0, lastError)
+	default:
+		result = &CheckResult{Status: "up"}
+	}
+	result.Details = details
+
+	return result
+}
+
+func (s *HeartbeatService) Validate(service *models.Service) error {
+	if service.Config.Period <= 0 {
+		return fmt.Errorf("heartbeat period must be positive")
+	}
+	if service.Config.Grace < 0 {
+		return fmt.Errorf("heartbeat grace must not be negative")
+	}
+	return nil
+}
+
+func heartbeatGrace(service *models.Service) time.Duration {
+	if service.Config.Grace > 0 {
+		return time.Duration(service.Config.Grace) * time.Second
+	}
+	return defaultHeartbeatGrace
+}
+
+// IsHeartbeat reports whether the service is a heartbeat monitor, matching
+// the type case-insensitively like LookupChecker.
+func IsHeartbeat(service *models.Service) bool {
+	return models.ServiceType(strings.ToLower(string(service.Type))) == models.ServiceTypeHeartbeat
+}
+
+func newPingToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ping token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/models"
+)
+
+// MaxHistoryBuckets bounds how many points one bucketed history query
+// returns.
+const MaxHistoryBuckets = 10000
+
+// MaxBucket is the widest bucket ParseBucket accepts.
+const MaxBucket = 366 * 24 * time.Hour
+
+// HistoryQuery selects health checks of a service checked in [From, To).
+// Statuses, when set, keeps only checks with one of them.
+type HistoryQuery struct {
+	From     time.Time
+	To       time.Time
+	Statuses []string
+	Limit    int
+	Cursor   string // from a previous page, empty for the first
+}
+
+// GetHealthHistory returns a page of checks matching the query, newest
+// first, and the cursor for the next page, which is empty on the last page.
+func (s *HealthCheckService) GetHealthHistory(ctx context.Context, serviceID int64, q HistoryQuery) ([]*models.HealthCheck, string, error) {
+	var (
+		afterTime interface{}
+		afterID   int64
+	)
+	if q.Cursor != "" {
+		t, id, err := decodeHistoryCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		afterTime, afterID = t, id
+	}
+
+	// Keyset pagination: the next page starts strictly after the last check
+	// returned, so rows arriving meanwhile do not shift pages
+	query := `
+		SELECT id, service_id, status, response_time, error, details, timing, in_maintenance, checked_at
+		FROM health_checks
+		WHERE service_id = $1
+		  AND checked_at >= $2 AND checked_at < $3
+		  AND ($4::text[] IS NULL OR status = ANY($4))
+		  AND ($5::timestamptz IS NULL OR (checked_at, id) < ($5, $6))
+		ORDER BY checked_at DESC, id DESC
+		LIMIT $7
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, serviceID, q.From, q.To, pq.Array(q.Statuses), afterTime, afterID, q.Limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get health history: %w", err)
+	}
+	defer rows.Close()
+
+	checks := []*models.HealthCheck{}
+	for rows.Next() {
+		var check models.HealthCheck
+		err := rows.Scan(
+			&check.ID,
+			&check.ServiceID,
+			&check.Status,
+			&check.ResponseTime,
+			&check.Error,
+			&check.Details,
+			&check.Timing,
+			&check.InMaintenance,
+			&check.CheckedAt,
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan health check: %w", err)
+		}
+		checks = append(checks, &check)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating health checks: %w", err)
+	}
+
+	var next string
+	if len(checks) > q.Limit {
+		checks = checks[:q.Limit]
+		last := checks[len(checks)-1]
+		next = encodeHistoryCursor(last.CheckedAt, last.ID)
+	}
+
+	return checks, next, nil
+}
+
+// GetHealthBuckets aggregates the checks matching the query into buckets of
+// the given width, aligned to the Unix epoch, oldest first, and returns the
+// width used. Buckets without checks are left out. Limit and Cursor are
+// ignored.
+//
+// Buckets of whole hours or days, and any buckets reaching back past the
+// retention of raw checks, are read from rollups; the width is then rounded
+// up to whole rollup periods.
+func (s *HealthCheckService) GetHealthBuckets(ctx context.Context, serviceID int64, q HistoryQuery, bucket time.Duration) ([]models.HealthBucket, time.Duration, error) {
+	if bucket < time.Second {
+		return nil, 0, fmt.Errorf("bucket must be at least 1s")
+	}
+	if q.To.Sub(q.From)/bucket > MaxHistoryBuckets {
+		return nil, 0, fmt.Errorf("range spans more than %d buckets", MaxHistoryBuckets)
+	}
+
+	state, err := loadRollupState(ctx, s.db)
+	if err != nil {
+		return nil, 0, err
+	}
+	if level := bucketLevel(bucket, q.From, state); level != nil {
+		bucket = (bucket + level.period - 1) / level.period * level.period
+		buckets, err := s.getRolledUpBuckets(ctx, serviceID, q, bucket, *level, state)
+		return buckets, bucket, err
+	}
+
+	buckets, err := s.getRawBuckets(ctx, serviceID, q, bucket)
+	return buckets, bucket, err
+}
+
+// bucketLevel returns the rollup to read buckets from, or nil for raw
+// checks.
+func bucketLevel(bucket time.Duration, from time.Time, state rollupState) *rollupLevel {
+	hourly := bucket%hourlyRollup.period == 0 || from.Before(state.rawFrom)
+	daily := bucket%dailyRollup.period == 0 || (hourly && from.Before(state.hourlyFrom))
+
+	switch {
+	case daily:
+		return &dailyRollup
+	case hourly:
+		return &hourlyRollup
+	}
+	return nil
+}
+
+func (s *HealthCheckService) getRawBuckets(ctx context.Context, serviceID int64, q HistoryQuery, bucket time.Duration) ([]models.HealthBucket, error) {
+	query := `
+		SELECT
+			to_timestamp(floor(extract(epoch FROM checked_at)::float8 / $5::float8) * $5::float8) AS bucket,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'up'),
+			COUNT(*) FILTER (WHERE status = 'degraded'),
+			COUNT(*) FILTER (WHERE status = 'down'),
+			COUNT(*) FILTER (WHERE status NOT IN ('up', 'degraded', 'down')),
+			AVG(response_time) FILTER (WHERE status IN ('up', 'degraded')),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded')),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded')),
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded')),
+			bool_or(in_maintenance)
+		FROM health_checks
+		WHERE service_id = $1
+		  AND checked_at >= $2 AND checked_at < $3
+		  AND ($4::text[] IS NULL OR status = ANY($4))
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, serviceID, q.From, q.To, pq.Array(q.Statuses), bucket.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health history: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := []models.HealthBucket{}
+	for rows.Next() {
+		var b models.HealthBucket
+		err := rows.Scan(
+			&b.Start,
+			&b.Count,
+			&b.Up,
+			&b.Degraded,
+			&b.Down,
+			&b.Unknown,
+			&b.AvgResponseTime,
+			&b.P50,
+			&b.P95,
+			&b.P99,
+			&b.InMaintenance,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan health bucket: %w", err)
+		}
+		buckets = append(buckets, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating health buckets: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// getRolledUpBuckets aggregates rollups of the level into buckets, adding
+// raw checks for the periods not rolled up yet. Rolled up periods only
+// count when they start within the range. Latency statistics are weighted
+// averages of the periods' and cover both up and degraded checks whenever
+// either is selected.
+func (s *HealthCheckService) getRolledUpBuckets(ctx context.Context, serviceID int64, q HistoryQuery, bucket time.Duration, level rollupLevel, state rollupState) ([]models.HealthBucket, error) {
+	// Rollups are read up to split and raw checks from there on
+	split := state.rolledTo(level)
+	if split.Before(q.From) {
+		split = q.From
+	}
+	if split.After(q.To) {
+		split = q.To
+	}
+
+	query := `
+		WITH periods AS (
+			SELECT bucket, in_maintenance, up, degraded, down, unknown, response_time_sum, p50, p95, p99
+			FROM ` + level.table + `
+			WHERE service_id = $1 AND bucket >= $2 AND bucket < $5
+			UNION ALL
+			SELECT
+				to_timestamp(floor(extract(epoch FROM checked_at)::float8 / $6::float8) * $6::float8),
+				in_maintenance,
+				COUNT(*) FILTER (WHERE status = 'up'),
+				COUNT(*) FILTER (WHERE status = 'degraded'),
+				COUNT(*) FILTER (WHERE status = 'down'),
+				COUNT(*) FILTER (WHERE status NOT IN ('up', 'degraded', 'down')),
+				COALESCE(SUM(response_time) FILTER (WHERE status IN ('up', 'degraded')), 0),
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded')),
+				percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded')),
+				percentile_cont(0.99) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded'))
+			FROM health_checks
+			WHERE service_id = $1 AND checked_at >= $5 AND checked_at < $3
+			GROUP BY 1, in_maintenance
+		),
+		selected AS (
+			SELECT
+				$4::text[] IS NULL OR 'up' = ANY($4) AS up,
+				$4::text[] IS NULL OR 'degraded' = ANY($4) AS degraded,
+				$4::text[] IS NULL OR 'down' = ANY($4) AS down,
+				$4::text[] IS NULL OR 'unknown' = ANY($4) AS unknown
+		)
+		SELECT
+			to_timestamp(floor(extract(epoch FROM p.bucket)::float8 / $7::float8) * $7::float8) AS point_start,
+			SUM(CASE WHEN f.up THEN p.up ELSE 0 END),
+			SUM(CASE WHEN f.degraded THEN p.degraded ELSE 0 END),
+			SUM(CASE WHEN f.down THEN p.down ELSE 0 END),
+			SUM(CASE WHEN f.unknown THEN p.unknown ELSE 0 END),
+			CASE WHEN f.up OR f.degraded THEN
+				SUM(p.response_time_sum)::float8 / NULLIF(SUM(p.up + p.degraded), 0) END,
+			CASE WHEN f.up OR f.degraded THEN
+				SUM(p.p50 * (p.up + p.degraded)) / NULLIF(SUM(p.up + p.degraded) FILTER (WHERE p.p50 IS NOT NULL), 0) END,
+			CASE WHEN f.up OR f.degraded THEN
+				SUM(p.p95 * (p.up + p.degraded)) / NULLIF(SUM(p.up + p.degraded) FILTER (WHERE p.p95 IS NOT NULL), 0) END,
+			CASE WHEN f.up OR f.degraded THEN
+				SUM(p.p99 * (p.up + p.degraded)) / NULLIF(SUM(p.up + p.degraded) FILTER (WHERE p.p99 IS NOT NULL), 0) END,
+			bool_or(p.in_maintenance)
+		FROM periods p CROSS JOIN selected f
+		GROUP BY point_start, f.up, f.degraded, f.down, f.unknown
+		ORDER BY point_start
+	`
+
+	rows, err := s.db.QueryContext(ctx, query,
+		serviceID, q.From, q.To, pq.Array(q.Statuses), split, level.period.Seconds(), bucket.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health history: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := []models.HealthBucket{}
+	for rows.Next() {
+		var b models.HealthBucket
+		err := rows.Scan(
+			&b.Start,
+			&b.Up,
+			&b.Degraded,
+			&b.Down,
+			&b.Unknown,
+			&b.AvgResponseTime,
+			&b.P50,
+			&b.P95,
+			&b.P99,
+			&b.InMaintenance,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan health bucket: %w", err)
+		}
+		b.Count = b.Up + b.Degraded + b.Down + b.Unknown
+		if b.Count == 0 {
+			continue
+		}
+		buckets = append(buckets, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating health buckets: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// FormatBucket formats a bucket width in the largest unit ParseBucket
+// accepts that divides it.
+func FormatBucket(bucket time.Duration) string {
+	switch {
+	case bucket%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", bucket/(24*time.Hour))
+	case bucket%time.Hour == 0:
+		return fmt.Sprintf("%dh", bucket/time.Hour)
+	case bucket%time.Minute == 0:
+		return fmt.Sprintf("%dm", bucket/time.Minute)
+	}
+	return fmt.Sprintf("%ds", bucket/time.Second)
+}
+
+// ParseBucket parses a bucket width such as 30s, 5m, 1h or 1d, up to
+// MaxBucket.
+func ParseBucket(value string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+	}
+
+	value = strings.TrimSpace(value)
+	if len(value) < 2 {
+		return 0, fmt.Errorf("invalid bucket %q", value)
+	}
+	unit, ok := units[value[len(value)-1:]]
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if !ok || err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid bucket %q, use a number followed by s, m, h or d", value)
+	}
+	if n > int(MaxBucket/unit) {
+		return 0, fmt.Errorf("bucket %q is longer than %s", value, FormatBucket(MaxBucket))
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// History cursors encode the checked_at and id of the last check on a page.
+func encodeHistoryCursor(checkedAt time.Time, id int64) string {
+	raw := checkedAt.UTC().Format(time.RFC3339Nano) + "," + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeHistoryCursor(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), ",", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor")
+	}
+	checkedAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor")
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor")
+	}
+
+	return checkedAt, id, nil
+}
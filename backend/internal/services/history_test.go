@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBucket(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "30s", want: 30 * time.Second},
+		{value: "5m", want: 5 * time.Minute},
+		{value: "1h", want: time.Hour},
+		{value: "7d", want: 7 * 24 * time.Hour},
+		{value: " 90m ", want: 90 * time.Minute},
+		{value: "", wantErr: true},
+		{value: "h", wantErr: true},
+		{value: "10", wantErr: true},
+		{value: "0m", wantErr: true},
+		{value: "-5m", wantErr: true},
+		{value: "1.5h", wantErr: true},
+		{value: "2w", wantErr: true},
+		{value: "1h30m", wantErr: true},
+		{value: "366d", want: MaxBucket},
+		{value: "367d", wantErr: true},
+		{value: "8785h", wantErr: true},
+		{value: "36028797018963968s", wantErr: true},
+		{value: "9223372036854775807s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseBucket(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBucket(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBucket(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBucket(t *testing.T) {
+	tests := []struct {
+		bucket time.Duration
+		want   string
+	}{
+		{30 * time.Second, "30s"},
+		{90 * time.Second, "90s"},
+		{5 * time.Minute, "5m"},
+		{90 * time.Minute, "90m"},
+		{time.Hour, "1h"},
+		{36 * time.Hour, "36h"},
+		{48 * time.Hour, "2d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := FormatBucket(tt.bucket)
+			if got != tt.want {
+				t.Errorf("FormatBucket(%v) = %q, want %q", tt.bucket, got, tt.want)
+			}
+			if parsed, err := ParseBucket(got); err != nil || parsed != tt.bucket {
+				t.Errorf("ParseBucket(%q) = %v, %v, want %v", got, parsed, err, tt.bucket)
+			}
+		})
+	}
+}
+
+func TestBucketLevel(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	state := rollupState{
+		rawFrom:    now.Add(-7 * 24 * time.Hour),
+		hourlyFrom: now.Add(-90 * 24 * time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		bucket time.Duration
+		from   time.Time
+		state  rollupState
+		want   *rollupLevel
+	}{
+		{"minutes within raw retention", 5 * time.Minute, now.Add(-24 * time.Hour), state, nil},
+		{"whole hours", 2 * time.Hour, now.Add(-24 * time.Hour), state, &hourlyRollup},
+		{"whole days", 24 * time.Hour, now.Add(-24 * time.Hour), state, &dailyRollup},
+		{"minutes before raw retention", 5 * time.Minute, now.Add(-30 * 24 * time.Hour), state, &hourlyRollup},
+		{"hours before hourly retention", time.Hour, now.Add(-180 * 24 * time.Hour), state, &dailyRollup},
+		{"minutes before hourly retention", 5 * time.Minute, now.Add(-180 * 24 * time.Hour), state, &dailyRollup},
+		{"nothing pruned yet", 5 * time.Minute, now.Add(-365 * 24 * time.Hour), rollupState{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bucketLevel(tt.bucket, tt.from, tt.state)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil:
+				t.Errorf("bucketLevel = %v, want %v", got, tt.want)
+			case got.table != tt.want.table:
+				t.Errorf("bucketLevel = %s, want %s", got.table, tt.want.table)
+			}
+		})
+	}
+}
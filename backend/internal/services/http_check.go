@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+const (
+	maxRedirects        = 10
+	maxResponseBodySize = 1 << 20
+)
+
+// probeHTTP sends the configured request and compares the response status
+// against Config.ExpectedStatus / Config.AcceptedStatuses, defaulting to any
+// 2xx when neither is set, then evaluates Config.Assertions. HTTPS responses
+// also have their certificate inspected.
+func probeHTTP(ctx context.Context, service *models.Service) *CheckResult {
+	client, err := newHTTPClient(service)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(service))
+	defer cancel()
+
+	req, err := newHTTPRequest(ctx, service)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	timer := &httpTimer{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timer.trace()))
+
+	start := time.Now()
+
+	// Make request
+	resp, err := client.Do(req)
+	if err != nil {
+		// The phases reached show where the request failed, e.g. DNS or TLS
+		result := failedResult("down", 0, err.Error())
+		result.Timing = timer.timing(time.Now())
+		return result
+	}
+	defer resp.Body.Close()
+
+	// Calculate response time
+	responseTime := time.Since(start).Milliseconds()
+
+	// Read the body so the full transfer is part of the check
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	timing := timer.timing(time.Now())
+	if err != nil {
+		result := failedResult("down", responseTime, fmt.Sprintf("failed to read response body: %v", err))
+		result.Timing = timing
+		return result
+	}
+
+	ok, err := statusAccepted(resp.StatusCode, service.Config)
+	if err != nil {
+		result := failedResult("down", responseTime, err.Error())
+		result.Timing = timing
+		return result
+	}
+	if !ok {
+		result := failedResult("down", responseTime, fmt.Sprintf("HTTP %d", resp.StatusCode))
+		result.Timing = timing
+		return result
+	}
+
+	result := &CheckResult{
+		Status:       "up",
+		ResponseTime: responseTime,
+		Details:      models.CheckDetails{},
+		Timing:       timing,
+	}
+
+	if len(service.Config.Assertions) > 0 {
+		results, failures := evaluateAssertions(service.Config.Assertions, &httpResponse{
+			Header:       resp.Header,
+			Body:         body,
+			ResponseTime: responseTime,
+		})
+		result.Details["assertions"] = results
+		if len(failures) > 0 {
+			result.Status = "down"
+			result.Error = "assertion failed: " + strings.Join(failures, "; ")
+		}
+	}
+
+	if resp.TLS != nil {
+		roots := client.Transport.(*http.Transport).TLSClientConfig.RootCAs
+		cert := certificateResult(*resp.TLS, resp.Request.URL.Hostname(), roots, service.Config)
+		for key, value := range cert.Details {
+			result.Details[key] = value
+		}
+		result.Warning = cert.Warning
+		if result.Status == "up" && cert.Status != "up" {
+			result.Status = cert.Status
+			result.Error = cert.Error
+		}
+	}
+
+	if len(result.Details) == 0 {
+		result.Details = nil
+	}
+
+	return result
+}
+
+func validateHTTP(service *models.Service) error {
+	u, err := url.Parse(service.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("URL must start with http:// or https://")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("URL must include a host")
+	}
+
+	for _, pattern := range service.Config.AcceptedStatuses {
+		if _, err := matchStatus(0, pattern); err != nil {
+			return err
+		}
+	}
+	if _, err := newTLSConfig(service.Config); err != nil {
+		return err
+	}
+
+	return validateAssertions(service.Config.Assertions)
+}
+
+func newHTTPRequest(ctx context.Context, service *models.Service) (*http.Request, error) {
+	method := strings.ToUpper(service.Config.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if service.Config.Body != "" {
+		body = strings.NewReader(service.Config.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, service.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	for name, value := range service.Config.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "service-monitor")
+	}
+
+	return req, nil
+}
+
+// newHTTPClient builds a client for a single check. Keep-alives are disabled
+// so every check measures a fresh connection and nothing lingers between
+// checks.
+func newHTTPClient(service *models.Service) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(service.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.DisableKeepAlives = true
+
+	client := &http.Client{Transport: transport}
+
+	followRedirects := service.Config.FollowRedirects == nil || *service.Config.FollowRedirects
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !followRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+
+	return client, nil
+}
+
+func newTLSConfig(cfg models.ServiceConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	}
+
+	if cfg.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(cfg.CABundle)) {
+			return nil, fmt.Errorf("invalid CA bundle: no PEM certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// statusAccepted reports whether code matches the service's expected status.
+// AcceptedStatuses entries may be exact codes ("204"), ranges ("200-299") or
+// classes ("2xx").
+func statusAccepted(code int, cfg models.ServiceConfig) (bool, error) {
+	if cfg.ExpectedStatus == 0 && len(cfg.AcceptedStatuses) == 0 {
+		return code >= 200 && code < 300, nil
+	}
+
+	if cfg.ExpectedStatus != 0 && code == cfg.ExpectedStatus {
+		return true, nil
+	}
+
+	for _, pattern := range cfg.AcceptedStatuses {
+		ok, err := matchStatus(code, pattern)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func matchStatus(code int, pattern string) (bool, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+	if len(pattern) == 3 && strings.HasSuffix(pattern, "xx") {
+		class, err := strconv.Atoi(pattern[:1])
+		if err != nil {
+			return false, fmt.Errorf("invalid status pattern %q", pattern)
+		}
+		return code/100 == class, nil
+	}
+
+	if low, high, ok := strings.Cut(pattern, "-"); ok {
+		from, err := strconv.Atoi(strings.TrimSpace(low))
+		if err != nil {
+			return false, fmt.Errorf("invalid status range %q", pattern)
+		}
+		to, err := strconv.Atoi(strings.TrimSpace(high))
+		if err != nil {
+			return false, fmt.Errorf("invalid status range %q", pattern)
+		}
+		return code >= from && code <= to, nil
+	}
+
+	expected, err := strconv.Atoi(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid status code %q", pattern)
+	}
+	return code == expected, nil
+}
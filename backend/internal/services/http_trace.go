@@ -0,0 +1,86 @@
+package services
+
+import (
+	"crypto/tls"
+	"math"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// httpTimer records the phases of an HTTP request through httptrace. When
+// redirects are followed, only the final request's phases are kept, as they
+// belong to the response that was checked.
+type httpTimer struct {
+	mu sync.Mutex
+	httpPhases
+}
+
+type httpPhases struct {
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+func (t *httpTimer) trace() *httptrace.ClientTrace {
+	// Hooks may be called from dialing goroutines, hence the lock
+	set := func(field *time.Time) {
+		t.mu.Lock()
+		*field = time.Now()
+		t.mu.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.httpPhases = httpPhases{}
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			// With several addresses the dialer may race connections; time
+			// from the first attempt
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone:          func(string, string, error) { set(&t.connectDone) },
+		TLSHandshakeStart:    func() { set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { set(&t.firstByte) },
+	}
+}
+
+// timing returns the recorded phases, with transfer ending at done.
+func (t *httpTimer) timing(done time.Time) *models.CheckTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return &models.CheckTiming{
+		DNS:      phase(t.dnsStart, t.dnsDone),
+		Connect:  phase(t.connectStart, t.connectDone),
+		TLS:      phase(t.tlsStart, t.tlsDone),
+		TTFB:     phase(t.wroteRequest, t.firstByte),
+		Transfer: phase(t.firstByte, done),
+	}
+}
+
+// phase returns the milliseconds between start and end, rounded to a
+// microsecond, or 0 if the phase did not complete.
+func phase(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return math.Round(float64(end.Sub(start).Microseconds())) / 1000
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"service-monitor/internal/models"
+)
+
+const (
+	defaultPingCount    = 4
+	defaultDegradedLoss = 25.0
+	defaultDownLoss     = 100.0
+	minProbeTimeout     = 500 * time.Millisecond
+
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+type pingStats struct {
+	sent     int
+	received int
+	min      time.Duration
+	max      time.Duration
+	total    time.Duration
+}
+
+func (p *pingStats) add(rtt time.Duration) {
+	if p.received == 0 || rtt < p.min {
+		p.min = rtt
+	}
+	if rtt > p.max {
+		p.max = rtt
+	}
+	p.total += rtt
+	p.received++
+}
+
+func (p *pingStats) loss() float64 {
+	if p.sent == 0 {
+		return 100
+	}
+	return float64(p.sent-p.received) / float64(p.sent) * 100
+}
+
+func (p *pingStats) avg() time.Duration {
+	if p.received == 0 {
+		return 0
+	}
+	return p.total / time.Duration(p.received)
+}
+
+// probeICMP sends Config.PingCount echo requests to the service host and
+// records RTT and packet loss. The service is degraded once loss reaches
+// Config.DegradedLoss and down once it reaches Config.DownLoss.
+func probeICMP(ctx context.Context, service *models.Service) *CheckResult {
+	host, err := icmpHost(service.URL)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(service))
+	defer cancel()
+
+	ip, err := resolvePingTarget(ctx, host)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	count := service.Config.PingCount
+	if count <= 0 {
+		count = defaultPingCount
+	}
+
+	stats, err := ping(ctx, ip, count)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	loss := stats.loss()
+	details := models.CheckDetails{
+		"address":     ip.String(),
+		"sent":        stats.sent,
+		"received":    stats.received,
+		"packet_loss": loss,
+		"rtt_min_ms":  durationMillis(stats.min),
+		"rtt_avg_ms":  durationMillis(stats.avg()),
+		"rtt_max_ms":  durationMillis(stats.max),
+	}
+
+	degradedLoss, downLoss := lossThresholds(service.Config)
+
+	status, errorMsg := "up", ""
+	switch {
+	case loss >= downLoss:
+		status = "down"
+		errorMsg = fmt.Sprintf("%.0f%% packet loss (%d/%d received)", loss, stats.received, stats.sent)
+	case loss >= degradedLoss:
+		status = "degraded"
+		errorMsg = fmt.Sprintf("%.0f%% packet loss (%d/%d received)", loss, stats.received, stats.sent)
+	}
+
+	return &CheckResult{
+		Status:       status,
+		ResponseTime: stats.avg().Milliseconds(),
+		Error:        errorMsg,
+		Details:      details,
+	}
+}
+
+func validateICMP(service *models.Service) error {
+	if _, err := icmpHost(service.URL); err != nil {
+		return err
+	}
+	if service.Config.PingCount < 0 {
+		return fmt.Errorf("pingCount must not be negative")
+	}
+	for _, loss := range []float64{service.Config.DegradedLoss, service.Config.DownLoss} {
+		if loss < 0 || loss > 100 {
+			return fmt.Errorf("packet loss thresholds must be between 0 and 100")
+		}
+	}
+	// A service is down before it can be degraded otherwise
+	if degradedLoss, downLoss := lossThresholds(service.Config); degradedLoss > downLoss {
+		return fmt.Errorf("degradedLoss (%g%%) must not exceed downLoss (%g%%)", degradedLoss, downLoss)
+	}
+	return nil
+}
+
+// lossThresholds returns the packet loss percentages at which a service is
+// degraded and down, applying the defaults for unset thresholds.
+func lossThresholds(cfg models.ServiceConfig) (float64, float64) {
+	degradedLoss := cfg.DegradedLoss
+	if degradedLoss <= 0 {
+		degradedLoss = defaultDegradedLoss
+	}
+	downLoss := cfg.DownLoss
+	if downLoss <= 0 {
+		downLoss = defaultDownLoss
+	}
+	return degradedLoss, downLoss
+}
+
+// ping sends count echo requests to ip, one at a time, splitting the context
+// deadline evenly between probes.
+func ping(ctx context.Context, ip net.IP, count int) (*pingStats, error) {
+	conn, privileged, err := listenICMP(ip)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	probeTimeout := minProbeTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if perProbe := time.Until(deadline) / time.Duration(count); perProbe > probeTimeout {
+			probeTimeout = perProbe
+		}
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	proto := protocolICMP
+	if ip.To4() == nil {
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		proto = protocolIPv6ICMP
+	}
+
+	// Unprivileged sockets have their ID rewritten by the kernel, so only
+	// raw sockets can filter replies on it.
+	id := rand.Intn(0xffff)
+	stats := &pingStats{}
+	buf := make([]byte, 1500)
+
+	for seq := 1; seq <= count; seq++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		msg := icmp.Message{
+			Type: echoType,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("service-monitor")},
+		}
+		packet, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build echo request: %w", err)
+		}
+
+		start := time.Now()
+		if _, err := conn.WriteTo(packet, dst); err != nil {
+			return nil, fmt.Errorf("failed to send echo request: %w", err)
+		}
+		stats.sent++
+
+		deadline := start.Add(probeTimeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetReadDeadline(deadline)
+
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, fmt.Errorf("failed to read echo reply: %w", err)
+			}
+
+			if !peerIP(peer).Equal(ip) {
+				continue
+			}
+			reply, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil || reply.Type != replyType {
+				continue
+			}
+			echo, ok := reply.Body.(*icmp.Echo)
+			if !ok || echo.Seq != seq || (privileged && echo.ID != id) {
+				continue
+			}
+
+			stats.add(time.Since(start))
+			break
+		}
+	}
+
+	return stats, nil
+}
+
+// listenICMP opens an unprivileged datagram ICMP socket, falling back to a
+// raw socket when the kernel does not allow it (see net.ipv4.ping_group_range).
+func listenICMP(ip net.IP) (*icmp.PacketConn, bool, error) {
+	network, rawNetwork, address := "udp4", "ip4:icmp", "0.0.0.0"
+	if ip.To4() == nil {
+		network, rawNetwork, address = "udp6", "ip6:ipv6-icmp", "::"
+	}
+
+	conn, err := icmp.ListenPacket(network, address)
+	if err == nil {
+		return conn, false, nil
+	}
+
+	rawConn, rawErr := icmp.ListenPacket(rawNetwork, address)
+	if rawErr == nil {
+		return rawConn, true, nil
+	}
+
+	if errors.Is(rawErr, os.ErrPermission) {
+		return nil, false, fmt.Errorf("ICMP not permitted: unprivileged socket failed (%v) and raw socket requires CAP_NET_RAW", err)
+	}
+	return nil, false, fmt.Errorf("failed to open ICMP socket: %w", rawErr)
+}
+
+func resolvePingTarget(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return addr.IP, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	return addrs[0].IP, nil
+}
+
+// icmpHost extracts the host to ping from a service URL. A bare hostname or
+// IP is used as is; URL forms such as "icmp://host" use their hostname.
+func icmpHost(rawURL string) (string, error) {
+	host := strings.TrimSpace(rawURL)
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil {
+			return "", fmt.Errorf("invalid service URL: %w", err)
+		}
+		host = u.Hostname()
+	}
+	if host == "" {
+		return "", fmt.Errorf("invalid ICMP host %q", rawURL)
+	}
+
+	return host, nil
+}
+
+func peerIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"service-monitor/internal/models"
+)
+
+func TestValidateICMP(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		cfg     models.ServiceConfig
+		wantErr bool
+	}{
+		{"defaults", "example.com", models.ServiceConfig{}, false},
+		{"host from URL", "icmp://10.0.0.1", models.ServiceConfig{}, false},
+		{"no host", "", models.ServiceConfig{}, true},
+		{"negative count", "example.com", models.ServiceConfig{PingCount: -1}, true},
+		{"thresholds in order", "example.com", models.ServiceConfig{DegradedLoss: 10, DownLoss: 50}, false},
+		{"equal thresholds", "example.com", models.ServiceConfig{DegradedLoss: 50, DownLoss: 50}, false},
+		{"degraded above down", "example.com", models.ServiceConfig{DegradedLoss: 60, DownLoss: 50}, true},
+		{"down below default degraded", "example.com", models.ServiceConfig{DownLoss: 20}, true},
+		{"degraded below default down", "example.com", models.ServiceConfig{DegradedLoss: 90}, false},
+		{"negative loss", "example.com", models.ServiceConfig{DegradedLoss: -5}, true},
+		{"loss above 100", "example.com", models.ServiceConfig{DownLoss: 150}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateICMP(&models.Service{URL: tt.url, Config: tt.cfg})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateICMP error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
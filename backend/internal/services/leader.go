@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	leaderLockKey  = "{leader}:lock"  // holder ID, expires with the lease
+	leaderFenceKey = "{leader}:fence" // incremented on every election
+)
+
+var acquireLeaderScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+var renewLeaderScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var releaseLeaderScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// LeaderElector elects one process among the API replicas sharing a Redis to
+// run singleton work such as scheduling and escalations. Leadership is a
+// Redis key with a lease that the leader keeps renewing; if the leader dies
+// or loses Redis, the lease expires and another replica takes over.
+//
+// Each election hands the new leader a fencing token that is larger than any
+// earlier one. Work that must never be done twice records the token in the
+// database and refuses to proceed once a larger token has taken it over, so a
+// leader that stalled past its lease cannot act after being replaced.
+type LeaderElector struct {
+	client *redis.Client
+	id     string
+	ttl    time.Duration
+}
+
+func NewLeaderElector(client *redis.Client, ttl time.Duration) *LeaderElector {
+	host, _ := os.Hostname()
+	return &LeaderElector{
+		client: client,
+		id:     fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+		ttl:    ttl,
+	}
+}
+
+// Run campaigns for leadership until ctx is cancelled. Whenever this process
+// is elected, lead is called with a context that is cancelled when
+// leadership is lost and with the election's fencing token. If lead returns
+// while still leader, leadership is given up so another replica can try.
+// Run returns once lead has returned and the lease has been released.
+func (e *LeaderElector) Run(ctx context.Context, lead func(ctx context.Context, token int64)) {
+	interval := e.ttl / 3
+
+	for ctx.Err() == nil {
+		token, err := e.acquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Leader election failed: %v", err)
+		}
+
+		if token > 0 {
+			log.Printf("Elected leader with fencing token %d", token)
+			e.holdLeadership(ctx, token, lead)
+			log.Printf("No longer leader")
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// holdLeadership runs lead and renews the lease until ctx is cancelled, lead
+// returns or the lease can no longer be guaranteed. Lead's context is
+// cancelled as soon as the lease might have expired, even while a renewal is
+// still waiting on Redis.
+func (e *LeaderElector) holdLeadership(ctx context.Context, token int64, lead func(ctx context.Context, token int64)) {
+	interval := e.ttl / 3
+	// The lease is only trusted until shortly before it could have expired
+	// in Redis, measured from when the last successful renewal was sent.
+	deadline := time.Now().Add(e.ttl - interval)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	lapse := time.AfterFunc(time.Until(deadline), cancel)
+	defer lapse.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leaderCtx, token)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-done:
+			break loop
+		case <-ticker.C:
+		}
+
+		sent := time.Now()
+		renewCtx, cancelRenew := context.WithDeadline(ctx, deadline)
+		held, err := e.renew(renewCtx)
+		cancelRenew()
+		if err != nil {
+			if ctx.Err() != nil {
+				break loop
+			}
+			log.Printf("Failed to renew leader lease: %v", err)
+			if !time.Now().Before(deadline) {
+				break loop
+			}
+			continue
+		}
+		if !held {
+			log.Printf("Leader lease was taken over")
+			break loop
+		}
+		deadline = sent.Add(e.ttl - interval)
+		// Reset fails if the lease lapsed, and lead was cancelled, just
+		// before the renewal returned
+		if !lapse.Reset(time.Until(deadline)) {
+			break loop
+		}
+	}
+
+	cancel()
+	<-done
+
+	releaseCtx, cancelRelease := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelRelease()
+	if err := e.release(releaseCtx); err != nil {
+		log.Printf("Failed to release leader lease: %v", err)
+	}
+}
+
+func (e *LeaderElector) acquire(ctx context.Context) (int64, error) {
+	token, err := acquireLeaderScript.Run(ctx, e.client,
+		[]string{leaderLockKey, leaderFenceKey},
+		e.id, e.ttl.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire leader lease: %w", err)
+	}
+
+	return token, nil
+}
+
+func (e *LeaderElector) renew(ctx context.Context) (bool, error) {
+	held, err := renewLeaderScript.Run(ctx, e.client,
+		[]string{leaderLockKey},
+		e.id, e.ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew leader lease: %w", err)
+	}
+
+	return held == 1, nil
+}
+
+func (e *LeaderElector) release(ctx context.Context) error {
+	if err := releaseLeaderScript.Run(ctx, e.client, []string{leaderLockKey}, e.id).Err(); err != nil {
+		return fmt.Errorf("failed to release leader lease: %w", err)
+	}
+
+	return nil
+}
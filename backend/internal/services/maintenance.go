@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"service-monitor/internal/models"
+)
+
+// maintenancePollInterval is how often escalation rechecks whether a
+// service is still in maintenance.
+const maintenancePollInterval = 30 * time.Second
+
+// maxMaintenancePeriods bounds the occurrences of one recurring window
+// returned for a time range.
+const maxMaintenancePeriods = 1000
+
+type MaintenanceService struct {
+	db *sql.DB
+}
+
+func NewMaintenanceService(db *sql.DB) *MaintenanceService {
+	return &MaintenanceService{db: db}
+}
+
+const maintenanceColumns = `
+	w.id, w.name, w.starts_at, w.ends_at, w.schedule, w.timezone, w.duration,
+	w.service_ids, w.tags, w.created_at, w.updated_at
+`
+
+func (s *MaintenanceService) CreateWindow(ctx context.Context, window *models.MaintenanceWindow) error {
+	query := `
+		INSERT INTO maintenance_windows (name, starts_at, ends_at, schedule, timezone, duration, service_ids, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := s.db.QueryRowContext(ctx, query,
+		window.Name,
+		window.StartsAt,
+		window.EndsAt,
+		window.Schedule,
+		window.Timezone,
+		window.Duration,
+		pq.Array(scopeIDs(window.ServiceIDs)),
+		pq.Array(scopeTags(window.Tags)),
+	).Scan(&window.ID, &window.CreatedAt, &window.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create maintenance window: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MaintenanceService) GetWindow(ctx context.Context, id int64) (*models.MaintenanceWindow, error) {
+	query := `SELECT ` + maintenanceColumns + ` FROM maintenance_windows w WHERE w.id = $1`
+
+	window, err := scanWindow(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("maintenance window not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance window: %w", err)
+	}
+
+	return window, nil
+}
+
+func (s *MaintenanceService) ListWindows(ctx context.Context) ([]*models.MaintenanceWindow, error) {
+	query := `SELECT ` + maintenanceColumns + ` FROM maintenance_windows w ORDER BY w.id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []*models.MaintenanceWindow{}
+	for rows.Next() {
+		window, err := scanWindow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance window: %w", err)
+		}
+		windows = append(windows, window)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance windows: %w", err)
+	}
+
+	return windows, nil
+}
+
+func (s *MaintenanceService) UpdateWindow(ctx context.Context, window *models.MaintenanceWindow) error {
+	query := `
+		UPDATE maintenance_windows
+		SET name = $1, starts_at = $2, ends_at = $3, schedule = $4, timezone = $5,
+			duration = $6, service_ids = $7, tags = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $9
+		RETURNING created_at, updated_at
+	`
+
+	err := s.db.QueryRowContext(ctx, query,
+		window.Name,
+		window.StartsAt,
+		window.EndsAt,
+		window.Schedule,
+		window.Timezone,
+		window.Duration,
+		pq.Array(scopeIDs(window.ServiceIDs)),
+		pq.Array(scopeTags(window.Tags)),
+		window.ID,
+	).Scan(&window.CreatedAt, &window.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("maintenance window not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update maintenance window: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MaintenanceService) DeleteWindow(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance window: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("maintenance window not found")
+	}
+
+	return nil
+}
+
+// ValidateWindow checks that a window is either one-off, with a start before
+// its end, or recurring, with a valid schedule and a positive duration.
+func (s *MaintenanceService) ValidateWindow(window *models.MaintenanceWindow) error {
+	window.Name = strings.TrimSpace(window.Name)
+	if window.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if window.StartsAt != nil && window.EndsAt != nil && !window.StartsAt.Before(*window.EndsAt) {
+		return fmt.Errorf("startsAt must be before endsAt")
+	}
+
+	if strings.TrimSpace(window.Schedule) == "" {
+		if window.StartsAt == nil || window.EndsAt == nil {
+			return fmt.Errorf("a one-off window requires startsAt and endsAt, a recurring one a schedule")
+		}
+		if window.Duration != 0 || window.Timezone != "" {
+			return fmt.Errorf("duration and timezone only apply to recurring windows")
+		}
+		return nil
+	}
+
+	if _, err := parseSchedule(window.Schedule, window.Timezone); err != nil {
+		return err
+	}
+	if window.Duration <= 0 {
+		return fmt.Errorf("a recurring window requires a duration in minutes")
+	}
+
+	return nil
+}
+
+// ActiveWindow returns a maintenance window covering the service at the
+// given time, or nil if there is none.
+func (s *MaintenanceService) ActiveWindow(ctx context.Context, serviceID int64, at time.Time) (*models.MaintenanceWindow, error) {
+	windows, err := s.windowsInScope(ctx, serviceID, at, at)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, window := range windows {
+		if windowOpen(window, at) {
+			return window, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Periods returns the occurrences of the service's maintenance windows that
+// overlap [from, to), clipped to it and ordered by start.
+func (s *MaintenanceService) Periods(ctx context.Context, serviceID int64, from, to time.Time) ([]models.MaintenancePeriod, error) {
+	windows, err := s.windowsInScope(ctx, serviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := []models.MaintenancePeriod{}
+	for _, window := range windows {
+		periods = append(periods, windowPeriods(window, from, to)...)
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Start.Before(periods[j].Start) })
+
+	return periods, nil
+}
+
+// windowsInScope returns the windows covering the service whose bounds
+// overlap [from, to]. Recurring windows may still be closed throughout.
+func (s *MaintenanceService) windowsInScope(ctx context.Context, serviceID int64, from, to time.Time) ([]*models.MaintenanceWindow, error) {
+	query := `
+		SELECT ` + maintenanceColumns + `
+		FROM maintenance_windows w
+		JOIN services s ON s.id = $1
+		WHERE (w.starts_at IS NULL OR w.starts_at <= $3)
+		  AND (w.ends_at IS NULL OR w.ends_at > $2)
+		  AND (
+			(cardinality(w.service_ids) = 0 AND cardinality(w.tags) = 0)
+			OR s.id = ANY(w.service_ids)
+			OR w.tags && s.tags
+		  )
+		ORDER BY w.id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, serviceID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []*models.MaintenanceWindow
+	for rows.Next() {
+		window, err := scanWindow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance window: %w", err)
+		}
+		windows = append(windows, window)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance windows: %w", err)
+	}
+
+	return windows, nil
+}
+
+// windowOpen reports whether a window already known to be within its bounds
+// is open at t. A recurring window is open when its schedule fired within
+// the last Duration minutes.
+func windowOpen(window *models.MaintenanceWindow, t time.Time) bool {
+	if window.Schedule == "" {
+		return true
+	}
+
+	schedule, err := parseSchedule(window.Schedule, window.Timezone)
+	if err != nil {
+		return false
+	}
+	duration := time.Duration(window.Duration) * time.Minute
+	start := schedule.Next(t.Add(-duration))
+
+	return !start.IsZero() && !start.After(t)
+}
+
+// windowPeriods returns the occurrences of a window within [from, to),
+// clipped to the window's bounds and the range. At most
+// maxMaintenancePeriods occurrences are returned per window.
+func windowPeriods(window *models.MaintenanceWindow, from, to time.Time) []models.MaintenancePeriod {
+	if window.StartsAt != nil && window.StartsAt.After(from) {
+		from = *window.StartsAt
+	}
+	if window.EndsAt != nil && window.EndsAt.Before(to) {
+		to = *window.EndsAt
+	}
+	if !from.Before(to) {
+		return nil
+	}
+
+	period := func(start, end time.Time) models.MaintenancePeriod {
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		return models.MaintenancePeriod{WindowID: window.ID, Name: window.Name, Start: start, End: end}
+	}
+
+	if window.Schedule == "" {
+		return []models.MaintenancePeriod{period(from, to)}
+	}
+
+	schedule, err := parseSchedule(window.Schedule, window.Timezone)
+	if err != nil {
+		return nil
+	}
+	duration := time.Duration(window.Duration) * time.Minute
+
+	var periods []models.MaintenancePeriod
+	for start := schedule.Next(from.Add(-duration)); !start.IsZero() && start.Before(to); start = schedule.Next(start) {
+		if len(periods) == maxMaintenancePeriods {
+			break
+		}
+		// Occurrences longer than the schedule's interval overlap; merge them
+		if n := len(periods); n > 0 && !start.After(periods[n-1].End) {
+			periods[n-1] = period(periods[n-1].Start, start.Add(duration))
+			continue
+		}
+		periods = append(periods, period(start, start.Add(duration)))
+	}
+
+	return periods
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWindow(row rowScanner) (*models.MaintenanceWindow, error) {
+	var window models.MaintenanceWindow
+	var startsAt, endsAt sql.NullTime
+	var serviceIDs pq.Int64Array
+	var tags pq.StringArray
+
+	err := row.Scan(
+		&window.ID,
+		&window.Name,
+		&startsAt,
+		&endsAt,
+		&window.Schedule,
+		&window.Timezone,
+		&window.Duration,
+		&serviceIDs,
+		&tags,
+		&window.CreatedAt,
+		&window.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if startsAt.Valid {
+		window.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		window.EndsAt = &endsAt.Time
+	}
+	window.ServiceIDs = scopeIDs(serviceIDs)
+	window.Tags = scopeTags(tags)
+
+	return &window, nil
+}
+
+// scopeIDs and scopeTags turn nil into empty slices, so the columns never
+// hold NULL and the API always returns arrays.
+func scopeIDs(ids []int64) []int64 {
+	if ids == nil {
+		return []int64{}
+	}
+	return ids
+}
+
+func scopeTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
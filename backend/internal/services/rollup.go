@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	rollupInterval = 15 * time.Minute
+	// rollupDelay leaves time for checks still being recorded when a period
+	// ends, such as those delayed in the queue
+	rollupDelay    = 5 * time.Minute
+	pruneBatchSize = 10000
+	// MinRawRetention keeps raw checks long enough for the daily rollup of
+	// the previous day to read them.
+	MinRawRetention = 48 * time.Hour
+)
+
+// rollupLevel is one resolution of aggregated health checks.
+type rollupLevel struct {
+	table     string
+	period    time.Duration
+	watermark string // rollup state column: the end of the last rolled period
+}
+
+var (
+	hourlyRollup = rollupLevel{"health_check_rollups_hourly", time.Hour, "hourly_rolled_to"}
+	dailyRollup  = rollupLevel{"health_check_rollups_daily", 24 * time.Hour, "daily_rolled_to"}
+)
+
+// rollupState is the health_check_rollup_state row. Zero times are unset:
+// nothing rolled up, or nothing deleted yet.
+type rollupState struct {
+	hourlyRolledTo time.Time
+	dailyRolledTo  time.Time
+	rawFrom        time.Time // raw checks before this have been deleted
+	hourlyFrom     time.Time // hourly rollups before this have been deleted
+}
+
+func (st rollupState) rolledTo(level rollupLevel) time.Time {
+	if level == dailyRollup {
+		return st.dailyRolledTo
+	}
+	return st.hourlyRolledTo
+}
+
+// RollupService aggregates raw health checks into hourly and daily rollups
+// and deletes raw checks and hourly rollups past their retention. It must
+// only run on the elected leader.
+type RollupService struct {
+	db              *sql.DB
+	rawRetention    time.Duration
+	hourlyRetention time.Duration // 0 keeps hourly rollups forever
+}
+
+// NewRollupService creates a rollup service. Raw retention is at least
+// MinRawRetention, and hourly rollups are kept at least as long as raw
+// checks.
+func NewRollupService(db *sql.DB, rawRetention, hourlyRetention time.Duration) *RollupService {
+	if rawRetention < MinRawRetention {
+		rawRetention = MinRawRetention
+	}
+	if hourlyRetention != 0 && hourlyRetention < rawRetention {
+		hourlyRetention = rawRetention
+	}
+
+	return &RollupService{
+		db:              db,
+		rawRetention:    rawRetention,
+		hourlyRetention: hourlyRetention,
+	}
+}
+
+// Run rolls up and prunes health checks every rollupInterval until ctx is
+// cancelled.
+func (s *RollupService) Run(ctx context.Context) {
+	ticker := time.NewTicker(rollupInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to roll up health checks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce rolls up every complete period not yet rolled up and then deletes
+// data past retention, never deleting raw checks that are not rolled up.
+func (s *RollupService) RunOnce(ctx context.Context, now time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO health_check_rollup_state (id) VALUES (true) ON CONFLICT DO NOTHING`); err != nil {
+		return fmt.Errorf("failed to initialize rollup state: %w", err)
+	}
+
+	for _, level := range []rollupLevel{hourlyRollup, dailyRollup} {
+		if err := s.rollUp(ctx, level, now); err != nil {
+			return err
+		}
+	}
+
+	return s.prune(ctx, now)
+}
+
+// rollUp aggregates raw checks from the level's watermark to the end of the
+// last complete period. The state row is locked so that a previous leader
+// still finishing cannot roll up the same periods.
+func (s *RollupService) rollUp(ctx context.Context, level rollupLevel, now time.Time) error {
+	end := now.Add(-rollupDelay).Truncate(level.period)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var watermark sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT `+level.watermark+` FROM health_check_rollup_state FOR UPDATE`).Scan(&watermark)
+	if err != nil {
+		return fmt.Errorf("failed to get rollup state: %w", err)
+	}
+
+	start := watermark.Time
+	if !watermark.Valid {
+		var first sql.NullTime
+		if err := tx.QueryRowContext(ctx, `SELECT MIN(checked_at) FROM health_checks`).Scan(&first); err != nil {
+			return fmt.Errorf("failed to get first health check: %w", err)
+		}
+		if !first.Valid {
+			return nil
+		}
+		start = first.Time.Truncate(level.period)
+	}
+	if !start.Before(end) {
+		return nil
+	}
+
+	query := `
+		INSERT INTO ` + level.table + ` (
+			service_id, bucket, in_maintenance, count, up, degraded, down, unknown,
+			response_time_sum, p50, p95, p99
+		)
+		SELECT
+			service_id,
+			to_timestamp(floor(extract(epoch FROM checked_at)::float8 / $3::float8) * $3::float8) AS bucket,
+			in_maintenance,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'up'),
+			COUNT(*) FILTER (WHERE status = 'degraded'),
+			COUNT(*) FILTER (WHERE status = 'down'),
+			COUNT(*) FILTER (WHERE status NOT IN ('up', 'degraded', 'down')),
+			COALESCE(SUM(response_time) FILTER (WHERE status IN ('up', 'degraded')), 0),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded')),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded')),
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY response_time) FILTER (WHERE status IN ('up', 'degraded'))
+		FROM health_checks
+		WHERE checked_at >= $1 AND checked_at < $2
+		GROUP BY service_id, bucket, in_maintenance
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, start, end, level.period.Seconds()); err != nil {
+		return fmt.Errorf("failed to roll up health checks: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE health_check_rollup_state SET `+level.watermark+` = $1`, end); err != nil {
+		return fmt.Errorf("failed to update rollup state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to roll up health checks: %w", err)
+	}
+
+	return nil
+}
+
+// prune deletes raw checks and hourly rollups past retention. The retained
+// boundary is recorded before deleting, so readers switch to rollups before
+// the rows they would read disappear.
+func (s *RollupService) prune(ctx context.Context, now time.Time) error {
+	state, err := loadRollupState(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if state.hourlyRolledTo.IsZero() || state.dailyRolledTo.IsZero() {
+		return nil
+	}
+
+	rawCutoff := s.rawCutoff(now, state)
+	if state.rawFrom.Before(rawCutoff) {
+		if err := s.setRetained(ctx, "raw_from", rawCutoff); err != nil {
+			return err
+		}
+		if err := s.deleteRawChecks(ctx, rawCutoff); err != nil {
+			return err
+		}
+		state.rawFrom = rawCutoff
+	}
+
+	// Hourly rollups are only deleted where raw checks already are, so
+	// readers use daily rollups, hourly rollups and raw checks from oldest to
+	// newest
+	if s.hourlyRetention == 0 {
+		return nil
+	}
+	hourlyCutoff := s.hourlyCutoff(now, state)
+	if state.hourlyFrom.Before(hourlyCutoff) {
+		if err := s.setRetained(ctx, "hourly_from", hourlyCutoff); err != nil {
+			return err
+		}
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM health_check_rollups_hourly WHERE bucket < $1`, hourlyCutoff); err != nil {
+			return fmt.Errorf("failed to delete hourly rollups: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rawCutoff is the time before which raw checks may be deleted: retention
+// rounded down to the hour, but never past what both rollups have covered.
+func (s *RollupService) rawCutoff(now time.Time, state rollupState) time.Time {
+	return earliest(now.Add(-s.rawRetention).Truncate(time.Hour), state.hourlyRolledTo, state.dailyRolledTo)
+}
+
+// hourlyCutoff is the time before which hourly rollups may be deleted:
+// retention rounded down to the day, but never past the daily rollup or the
+// oldest raw check kept.
+func (s *RollupService) hourlyCutoff(now time.Time, state rollupState) time.Time {
+	return earliest(now.Add(-s.hourlyRetention).Truncate(24*time.Hour), state.dailyRolledTo, state.rawFrom)
+}
+
+func (s *RollupService) setRetained(ctx context.Context, column string, from time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE health_check_rollup_state SET `+column+` = $1`, from)
+	if err != nil {
+		return fmt.Errorf("failed to update rollup state: %w", err)
+	}
+	return nil
+}
+
+// deleteRawChecks deletes checks before cutoff in batches, so a large
+// backlog does not hold locks on health_checks for long.
+func (s *RollupService) deleteRawChecks(ctx context.Context, cutoff time.Time) error {
+	query := `
+		DELETE FROM health_checks
+		WHERE id IN (SELECT id FROM health_checks WHERE checked_at < $1 LIMIT $2)
+	`
+
+	for {
+		result, err := s.db.ExecContext(ctx, query, cutoff, pruneBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to delete health checks: %w", err)
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if deleted < pruneBatchSize {
+			return nil
+		}
+	}
+}
+
+func loadRollupState(ctx context.Context, db *sql.DB) (rollupState, error) {
+	query := `
+		SELECT hourly_rolled_to, daily_rolled_to, raw_from, hourly_from
+		FROM health_check_rollup_state
+	`
+
+	var hourlyRolledTo, dailyRolledTo, rawFrom, hourlyFrom sql.NullTime
+	err := db.QueryRowContext(ctx, query).Scan(&hourlyRolledTo, &dailyRolledTo, &rawFrom, &hourlyFrom)
+	if err == sql.ErrNoRows {
+		return rollupState{}, nil
+	}
+	if err != nil {
+		return rollupState{}, fmt.Errorf("failed to get rollup state: %w", err)
+	}
+
+	return rollupState{
+		hourlyRolledTo: hourlyRolledTo.Time,
+		dailyRolledTo:  dailyRolledTo.Time,
+		rawFrom:        rawFrom.Time,
+		hourlyFrom:     hourlyFrom.Time,
+	}, nil
+}
+
+func earliest(t time.Time, others ...time.Time) time.Time {
+	for _, other := range others {
+		if other.Before(t) {
+			t = other
+		}
+	}
+	return t
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEarliest(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(n int) time.Time { return base.Add(time.Duration(n) * time.Hour) }
+
+	tests := []struct {
+		name   string
+		t      time.Time
+		others []time.Time
+		want   time.Time
+	}{
+		{"alone", hour(3), nil, hour(3)},
+		{"first is earliest", hour(1), []time.Time{hour(2), hour(3)}, hour(1)},
+		{"later is earliest", hour(3), []time.Time{hour(2), hour(1)}, hour(1)},
+		{"equal", hour(2), []time.Time{hour(2)}, hour(2)},
+		{"zero time", hour(2), []time.Time{{}}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := earliest(tt.t, tt.others...); !got.Equal(tt.want) {
+				t.Errorf("earliest = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRollupServiceRetention(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		raw        time.Duration
+		hourly     time.Duration
+		wantRaw    time.Duration
+		wantHourly time.Duration
+	}{
+		{"as configured", 7 * day, 90 * day, 7 * day, 90 * day},
+		{"raw below minimum", time.Hour, 90 * day, MinRawRetention, 90 * day},
+		{"hourly below raw", 30 * day, 7 * day, 30 * day, 30 * day},
+		{"hourly below minimum raw", 0, day, MinRawRetention, MinRawRetention},
+		{"hourly kept forever", 7 * day, 0, 7 * day, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewRollupService(nil, tt.raw, tt.hourly)
+			if s.rawRetention != tt.wantRaw || s.hourlyRetention != tt.wantHourly {
+				t.Errorf("retention = %v, %v, want %v, %v", s.rawRetention, s.hourlyRetention, tt.wantRaw, tt.wantHourly)
+			}
+		})
+	}
+}
+
+func TestRetentionCutoffs(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Date(2024, 6, 10, 14, 37, 0, 0, time.UTC)
+	s := NewRollupService(nil, 3*day, 30*day)
+
+	tests := []struct {
+		name       string
+		state      rollupState
+		wantRaw    time.Time
+		wantHourly time.Time
+	}{
+		{
+			name: "rollups caught up",
+			state: rollupState{
+				hourlyRolledTo: time.Date(2024, 6, 10, 14, 0, 0, 0, time.UTC),
+				dailyRolledTo:  time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC),
+				rawFrom:        time.Date(2024, 6, 7, 14, 0, 0, 0, time.UTC),
+			},
+			wantRaw:    time.Date(2024, 6, 7, 14, 0, 0, 0, time.UTC),
+			wantHourly: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily rollup behind",
+			state: rollupState{
+				hourlyRolledTo: time.Date(2024, 6, 10, 14, 0, 0, 0, time.UTC),
+				dailyRolledTo:  time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC),
+				rawFrom:        time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC),
+			},
+			wantRaw:    time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC),
+			wantHourly: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "hourly rollup behind",
+			state: rollupState{
+				hourlyRolledTo: time.Date(2024, 6, 6, 3, 0, 0, 0, time.UTC),
+				dailyRolledTo:  time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC),
+			},
+			wantRaw:    time.Date(2024, 6, 6, 3, 0, 0, 0, time.UTC),
+			wantHourly: time.Time{},
+		},
+		{
+			name: "raw checks not pruned to retention yet",
+			state: rollupState{
+				hourlyRolledTo: time.Date(2024, 6, 10, 14, 0, 0, 0, time.UTC),
+				dailyRolledTo:  time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC),
+				rawFrom:        time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantRaw:    time.Date(2024, 6, 7, 14, 0, 0, 0, time.UTC),
+			wantHourly: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.rawCutoff(now, tt.state); !got.Equal(tt.wantRaw) {
+				t.Errorf("raw cutoff = %v, want %v", got, tt.wantRaw)
+			}
+			if got := s.hourlyCutoff(now, tt.state); !got.Equal(tt.wantHourly) {
+				t.Errorf("hourly cutoff = %v, want %v", got, tt.wantHourly)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"service-monitor/internal/models"
+)
+
+const (
+	defaultCheckInterval = 300 * time.Second
+	resyncInterval       = 30 * time.Second
+)
+
+// Scheduler runs health checks for every service on its configured interval
+// or cron schedule. It keeps one goroutine per service and reconciles them
+// against the services table whenever Refresh is called and periodically in
+// the background.
+type Scheduler struct {
+	db                 *sql.DB
+	serviceService     *ServiceService
+	healthCheckService *HealthCheckService
+	queue              *CheckQueue
+
+	mu      sync.Mutex
+	jobs    map[int64]*scheduledJob
+	refresh chan struct{}
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type scheduledJob struct {
+	updatedAt time.Time
+	interval  time.Duration
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func NewScheduler(db *sql.DB, serviceService *ServiceService, healthCheckService *HealthCheckService) *Scheduler {
+	return &Scheduler{
+		db:                 db,
+		serviceService:     serviceService,
+		healthCheckService: healthCheckService,
+		jobs:               make(map[int64]*scheduledJob),
+		refresh:            make(chan struct{}, 1),
+	}
+}
+
+// UseQueue makes the scheduler hand checks to workers through queue instead
+// of running them in this process. It must be called before Start.
+func (s *Scheduler) UseQueue(queue *CheckQueue) {
+	s.queue = queue
+}
+
+// Start loads all services and begins checking them. It returns once the
+// initial sync has completed; scheduling continues until Stop is called.
+func (s *Scheduler) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+
+	if err := s.sync(ctx); err != nil {
+		cancel()
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	return nil
+}
+
+// Stop cancels all scheduled checks and waits for in-flight checks to finish.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, job := range s.jobs {
+		job.cancel()
+		<-job.done
+		delete(s.jobs, id)
+	}
+}
+
+// Refresh asks the scheduler to reload services. It never blocks, so it is
+// safe to call from request handlers after a service is created, updated or
+// deleted.
+func (s *Scheduler) Refresh() {
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.refresh:
+		}
+
+		if err := s.sync(ctx); err != nil {
+			log.Printf("Scheduler sync failed: %v", err)
+		}
+	}
+}
+
+// sync reconciles running jobs with the services table: new services are
+// started, changed ones restarted and deleted ones stopped.
+func (s *Scheduler) sync(ctx context.Context) error {
+	services, err := s.serviceService.ListServices(ctx)
+	if err != nil {
+		return err
+	}
+
+	fallback := s.globalCheckInterval(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[int64]bool, len(services))
+	for _, service := range services {
+		seen[service.ID] = true
+		interval := checkInterval(service, fallback)
+
+		if job, ok := s.jobs[service.ID]; ok {
+			if job.updatedAt.Equal(service.UpdatedAt) && job.interval == interval {
+				continue
+			}
+			job.cancel()
+			<-job.done
+		}
+
+		s.jobs[service.ID] = s.startJob(ctx, service, interval)
+	}
+
+	for id, job := range s.jobs {
+		if !seen[id] {
+			job.cancel()
+			<-job.done
+			delete(s.jobs, id)
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) startJob(ctx context.Context, service *models.Service, interval time.Duration) *scheduledJob {
+	ctx, cancel := context.WithCancel(ctx)
+	job := &scheduledJob{
+		updatedAt: service.UpdatedAt,
+		interval:  interval,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	schedule, err := serviceSchedule(service)
+	if err != nil {
+		log.Printf("Invalid schedule for service %d, checking every %s instead: %v", service.ID, interval, err)
+	}
+	if schedule != nil {
+		go func() {
+			defer close(job.done)
+			s.runCron(ctx, service, schedule)
+		}()
+		return job
+	}
+
+	go func() {
+		defer close(job.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if s.queue != nil {
+				s.enqueueCheck(ctx, service, interval)
+			} else {
+				s.runCheck(ctx, service)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return job
+}
+
+// runCron checks the service at each time its cron schedule fires.
+func (s *Scheduler) runCron(ctx context.Context, service *models.Service, schedule cron.Schedule) {
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if s.queue != nil {
+			// Keep other schedulers from queueing the same run, but not the next one
+			s.enqueueCheck(ctx, service, schedule.Next(next).Sub(next))
+		} else {
+			s.runCheck(ctx, service)
+		}
+	}
+}
+
+func (s *Scheduler) runCheck(ctx context.Context, service *models.Service) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	check, err := s.healthCheckService.CheckService(ctx, service)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Health check for service %d failed: %v", service.ID, err)
+		}
+		return
+	}
+
+	if check.Status != "up" {
+		log.Printf("Service %d (%s) is %s: %s", service.ID, service.Name, check.Status, check.Error)
+	}
+}
+
+func (s *Scheduler) enqueueCheck(ctx context.Context, service *models.Service, interval time.Duration) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	if _, err := s.queue.Enqueue(ctx, service.ID, interval); err != nil && ctx.Err() == nil {
+		log.Printf("Failed to enqueue check for service %d: %v", service.ID, err)
+	}
+}
+
+// globalCheckInterval reads settings.check_interval, falling back to the
+// schema default when no settings row exists yet.
+func (s *Scheduler) globalCheckInterval(ctx context.Context) time.Duration {
+	var seconds int
+	err := s.db.QueryRowContext(ctx, `SELECT check_interval FROM settings LIMIT 1`).Scan(&seconds)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to read check interval setting: %v", err)
+		}
+		return defaultCheckInterval
+	}
+	if seconds <= 0 {
+		return defaultCheckInterval
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+func checkInterval(service *models.Service, fallback time.Duration) time.Duration {
+	if service.Config.CheckInterval > 0 {
+		return time.Duration(service.Config.CheckInterval) * time.Second
+	}
+	// Heartbeat checks only read the database, so late pings can be noticed
+	// promptly regardless of the global interval
+	if IsHeartbeat(service) && fallback > heartbeatCheckInterval {
+		return heartbeatCheckInterval
+	}
+	return fallback
+}
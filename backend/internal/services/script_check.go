@@ -0,0 +1,308 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-monitor/internal/config"
+	"service-monitor/internal/models"
+)
+
+// Nagios plugin exit codes.
+const (
+	nagiosOK       = 0
+	nagiosWarning  = 1
+	nagiosCritical = 2
+	nagiosUnknown  = 3
+)
+
+const (
+	maxScriptOutput  = 64 * 1024
+	maxScriptMessage = 512
+	scriptPath       = "/usr/local/bin:/usr/bin:/bin:/usr/lib/nagios/plugins:/usr/lib64/nagios/plugins"
+)
+
+// Defaults for ScriptSandbox limits left at zero.
+const (
+	defaultScriptCPUTime   = 10 * time.Second
+	defaultScriptMemory    = 256 << 20
+	defaultScriptProcesses = 32
+	defaultScriptFileSize  = 16 << 20
+)
+
+// scriptWrapper lowers the shell's resource limits before it runs the
+// script, which inherits them. The limits are set from inside the sandbox
+// because once the shell runs as the script user, the server could only
+// change them with CAP_SYS_RESOURCE. Shells name the process limit -u or -p.
+// Exit code 125 reports the check as unknown if a limit cannot be set.
+const scriptWrapper = `ulimit -t "$1" && ulimit -v "$2" && ulimit -f "$3" && { ulimit -u "$4" 2>&- || ulimit -p "$4"; } || exit 125
+exec /bin/sh -c "$5"`
+
+var errScriptsDisabled = errors.New("custom scripts are disabled; set checks.scripts.enabled in the server config to allow them")
+
+// ScriptSandbox confines custom scripts. Scripts run as User, chrooted into
+// Root, in a fresh directory under Root's /tmp, with CPU time, address
+// space, process count and file size limits, so a script can only read
+// files under Root that User may read. Root must hold /bin/sh and whatever
+// tools the scripts use. The server must run as root to drop to
+// User, which should be an account used for nothing else since the process
+// limit applies to all of its processes.
+type ScriptSandbox struct {
+	User      string
+	Root      string
+	CPUTime   time.Duration
+	Memory    int64 // bytes of address space
+	Processes int
+	FileSize  int64 // bytes
+}
+
+// SandboxFromConfig converts the scripts config into the sandbox custom
+// scripts run in.
+func SandboxFromConfig(cfg *config.ScriptsConfig) ScriptSandbox {
+	return ScriptSandbox{
+		User:      cfg.User,
+		Root:      cfg.Root,
+		CPUTime:   time.Duration(cfg.CPUSeconds) * time.Second,
+		Memory:    int64(cfg.MemoryMB) << 20,
+		Processes: cfg.MaxProcesses,
+		FileSize:  int64(cfg.MaxFileMB) << 20,
+	}
+}
+
+type scriptChecker struct {
+	sandbox ScriptSandbox
+	uid     uint32
+	gid     uint32
+}
+
+// NewScriptChecker returns the checker for custom services. Custom scripts
+// are disabled until one is registered with RegisterChecker, and it fails if
+// the sandbox cannot be enforced.
+func NewScriptChecker(sandbox ScriptSandbox) (Checker, error) {
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("the server must run as root to run scripts as another user")
+	}
+
+	account, err := user.Lookup(sandbox.User)
+	if err != nil {
+		return nil, fmt.Errorf("script user: %w", err)
+	}
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("script user: invalid uid %q", account.Uid)
+	}
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("script user: invalid gid %q", account.Gid)
+	}
+	if uid == 0 || gid == 0 {
+		return nil, fmt.Errorf("script user %q must not be root", sandbox.User)
+	}
+
+	if !filepath.IsAbs(sandbox.Root) || filepath.Clean(sandbox.Root) == "/" {
+		return nil, fmt.Errorf("script root must be an absolute path other than /")
+	}
+	sandbox.Root = filepath.Clean(sandbox.Root)
+	if _, err := os.Stat(filepath.Join(sandbox.Root, "bin", "sh")); err != nil {
+		return nil, fmt.Errorf("script root: %w", err)
+	}
+	if info, err := os.Stat(filepath.Join(sandbox.Root, "tmp")); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("script root must contain a tmp directory")
+	}
+
+	if sandbox.CPUTime <= 0 {
+		sandbox.CPUTime = defaultScriptCPUTime
+	}
+	if sandbox.Memory <= 0 {
+		sandbox.Memory = defaultScriptMemory
+	}
+	if sandbox.Processes <= 0 {
+		sandbox.Processes = defaultScriptProcesses
+	}
+	if sandbox.FileSize <= 0 {
+		sandbox.FileSize = defaultScriptFileSize
+	}
+
+	return &scriptChecker{sandbox: sandbox, uid: uint32(uid), gid: uint32(gid)}, nil
+}
+
+func (c *scriptChecker) Validate(service *models.Service) error {
+	if strings.TrimSpace(service.Config.CustomScript) == "" {
+		return fmt.Errorf("customScript is required for custom services")
+	}
+	return nil
+}
+
+// Check runs Config.CustomScript through /bin/sh in the sandbox and maps its
+// exit code using Nagios plugin conventions: 0 up, 1 degraded, 2 down,
+// anything else unknown. The script gets a minimal environment and its whole
+// process group is killed on timeout.
+func (c *scriptChecker) Check(ctx context.Context, service *models.Service) *CheckResult {
+	if strings.TrimSpace(service.Config.CustomScript) == "" {
+		return failedResult("unknown", 0, "no custom script configured")
+	}
+
+	timeout := checkTimeout(service)
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	hostDir, err := os.MkdirTemp(filepath.Join(c.sandbox.Root, "tmp"), "service-check-")
+	if err != nil {
+		return failedResult("unknown", 0, fmt.Sprintf("failed to create script directory: %v", err))
+	}
+	defer os.RemoveAll(hostDir)
+	if err := os.Chown(hostDir, int(c.uid), int(c.gid)); err != nil {
+		return failedResult("unknown", 0, fmt.Sprintf("failed to create script directory: %v", err))
+	}
+	workDir := "/tmp/" + filepath.Base(hostDir)
+
+	cmd := exec.CommandContext(probeCtx, "/bin/sh", "-c", scriptWrapper, "sh",
+		strconv.Itoa(int(c.sandbox.CPUTime.Seconds())),
+		strconv.FormatInt(c.sandbox.Memory>>10, 10),
+		strconv.FormatInt(c.sandbox.FileSize/512, 10),
+		strconv.Itoa(c.sandbox.Processes),
+		service.Config.CustomScript,
+	)
+	cmd.Dir = workDir
+	cmd.Env = scriptEnv(service, timeout, workDir)
+	output := &limitedBuffer{limit: maxScriptOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	sandboxProcess(cmd, c.sandbox.Root, c.uid, c.gid)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+	responseTime := time.Since(start).Milliseconds()
+
+	message, perfData := parsePluginOutput(output.String())
+	details := models.CheckDetails{}
+	if perfData != "" {
+		details["perfdata"] = perfData
+	}
+
+	if probeCtx.Err() == context.DeadlineExceeded {
+		details["exit_code"] = nagiosUnknown
+		return &CheckResult{
+			Status:       "unknown",
+			ResponseTime: responseTime,
+			Error:        fmt.Sprintf("script timed out after %s", timeout),
+			Details:      details,
+		}
+	}
+
+	exitCode := nagiosOK
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return &CheckResult{
+				Status:       "unknown",
+				ResponseTime: responseTime,
+				Error:        fmt.Sprintf("failed to run script: %v", err),
+				Details:      details,
+			}
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	details["exit_code"] = exitCode
+
+	status := "unknown"
+	switch exitCode {
+	case nagiosOK:
+		status = "up"
+	case nagiosWarning:
+		status = "degraded"
+	case nagiosCritical:
+		status = "down"
+	}
+
+	if status == "up" {
+		// Keep the plugin's status line for context but don't report it as an error
+		details["output"] = message
+		message = ""
+	}
+
+	return &CheckResult{
+		Status:       status,
+		ResponseTime: responseTime,
+		Error:        message,
+		Details:      details,
+	}
+}
+
+// scriptsDisabled is registered for custom services until the server config
+// enables scripts.
+func scriptsDisabled(ctx context.Context, service *models.Service) *CheckResult {
+	return failedResult("unknown", 0, errScriptsDisabled.Error())
+}
+
+func validateScriptsDisabled(service *models.Service) error {
+	return errScriptsDisabled
+}
+
+// scriptEnv builds the environment for a custom script. Nothing is inherited
+// from the server process; the sandbox, not the environment, is what keeps
+// the server's config and credentials out of the script's reach.
+func scriptEnv(service *models.Service, timeout time.Duration, workDir string) []string {
+	return []string{
+		"PATH=" + scriptPath,
+		"HOME=" + workDir,
+		"TMPDIR=" + workDir,
+		"SERVICE_ID=" + strconv.FormatInt(service.ID, 10),
+		"SERVICE_NAME=" + service.Name,
+		"SERVICE_TYPE=" + string(service.Type),
+		"SERVICE_URL=" + service.URL,
+		"SERVICE_TIMEOUT=" + strconv.Itoa(int(timeout.Seconds())),
+	}
+}
+
+// parsePluginOutput splits Nagios plugin output into the status text and
+// performance data ("TEXT | perfdata"), truncating the text.
+func parsePluginOutput(output string) (string, string) {
+	output = strings.TrimSpace(output)
+	firstLine, _, _ := strings.Cut(output, "\n")
+
+	message, perfData := output, ""
+	if text, perf, ok := strings.Cut(firstLine, "|"); ok {
+		message = strings.TrimSpace(text)
+		perfData = strings.TrimSpace(perf)
+	}
+
+	if len(message) > maxScriptMessage {
+		message = message[:maxScriptMessage] + "..."
+	}
+
+	return message, perfData
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a chatty script cannot exhaust memory.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package services
+
+import "os/exec"
+
+// sandboxProcess is never reached: NewScriptChecker requires root, which
+// os.Geteuid never reports on these platforms.
+func sandboxProcess(cmd *exec.Cmd, root string, uid, gid uint32) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package services
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// sandboxProcess makes the script start chrooted into root as uid and gid,
+// with no supplementary groups, in its own process group so that any
+// children it spawns can be killed along with it.
+func sandboxProcess(cmd *exec.Cmd, root string, uid, gid uint32) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    true,
+		Chroot:     root,
+		Credential: &syscall.Credential{Uid: uid, Gid: gid, Groups: []uint32{}},
+	}
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+const (
+	defaultFailureThreshold = 3
+	defaultSuccessThreshold = 1
+)
+
+// nextState advances the state machine by one check result:
+//
+//	up -> suspect -> down -> recovering -> up
+//
+// A failure while up or suspect counts towards failureThreshold; a success
+// while down or recovering counts towards successThreshold. Any success while
+// suspect returns straight to up, any failure while recovering straight to
+// down.
+func nextState(current models.ServiceState, healthy bool, successThreshold, failureThreshold int) models.ServiceState {
+	next := current
+	if healthy {
+		next.ConsecutiveSuccesses++
+		next.ConsecutiveFailures = 0
+	} else {
+		next.ConsecutiveFailures++
+		next.ConsecutiveSuccesses = 0
+	}
+
+	switch current.State {
+	case models.StateDown, models.StateRecovering:
+		switch {
+		case !healthy:
+			next.State = models.StateDown
+		case next.ConsecutiveSuccesses >= successThreshold:
+			next.State = models.StateUp
+		default:
+			next.State = models.StateRecovering
+		}
+	default:
+		switch {
+		case healthy:
+			next.State = models.StateUp
+		case next.ConsecutiveFailures >= failureThreshold:
+			next.State = models.StateDown
+		default:
+			next.State = models.StateSuspect
+		}
+	}
+
+	return next
+}
+
+// updateState applies a recorded check to the service's state machine,
+// persists the new state and raises or resolves alerts on transitions into
+// and out of down.
+func (s *HealthCheckService) updateState(ctx context.Context, service *models.Service, check *models.HealthCheck) error {
+	successThreshold := service.Config.SuccessThreshold
+	if successThreshold <= 0 {
+		successThreshold = defaultSuccessThreshold
+	}
+	failureThreshold := service.Config.FailureThreshold
+	if failureThreshold <= 0 {
+		failureThreshold = s.alertThreshold(ctx)
+		// A late or failed heartbeat is already conclusive
+		if IsHeartbeat(service) {
+			failureThreshold = 1
+		}
+	}
+
+	current, next, err := s.transitionState(ctx, service.ID, check, successThreshold, failureThreshold)
+	if err != nil {
+		return err
+	}
+
+	if next.State == current.State {
+		// An outage that began during maintenance alerts once it is over
+		if next.State == models.StateDown && current.AlertPending && !check.InMaintenance && s.alertService != nil {
+			return s.raiseDownAlert(ctx, service)
+		}
+		return nil
+	}
+
+	log.Printf("Service %d (%s) changed state: %s -> %s", service.ID, service.Name, current.State, next.State)
+	return s.handleTransition(ctx, service, current.State, next.State)
+}
+
+// transitionState advances the service's persisted state by one check in a
+// transaction holding the service_states row, so checks of the same service
+// recorded concurrently by the scheduler, workers, heartbeats and on-demand
+// checks are applied one after another. It returns the states before and
+// after the check.
+func (s *HealthCheckService) transitionState(ctx context.Context, serviceID int64, check *models.HealthCheck, successThreshold, failureThreshold int) (*models.ServiceState, *models.ServiceState, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A service's first check creates the row, holding it until commit;
+	// otherwise the existing row is locked
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO service_states (service_id, state, last_status)
+		VALUES ($1, $2, '')
+		ON CONFLICT (service_id) DO NOTHING
+	`, serviceID, models.StateUp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to save service state: %w", err)
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	current := &models.ServiceState{State: models.StateUp}
+	if created == 0 {
+		current, err = scanServiceState(tx.QueryRowContext(ctx, serviceStateQuery+` FOR UPDATE`, serviceID))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get service state: %w", err)
+		}
+	}
+
+	healthy := check.Status == "up" || check.Status == "degraded"
+	next := nextState(*current, healthy, successThreshold, failureThreshold)
+	next.LastStatus = check.Status
+	next.LastCheckedAt = check.CheckedAt
+	if next.State != current.State {
+		next.ChangedAt = check.CheckedAt
+	}
+
+	if err := saveServiceState(ctx, tx, serviceID, &next); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to save service state: %w", err)
+	}
+
+	return current, &next, nil
+}
+
+func (s *HealthCheckService) handleTransition(ctx context.Context, service *models.Service, from, to string) error {
+	if s.alertService == nil {
+		return nil
+	}
+
+	switch {
+	case to == models.StateDown:
+		return s.raiseDownAlert(ctx, service)
+	case to == models.StateUp && (from == models.StateDown || from == models.StateRecovering):
+		if err := s.alertService.ResolveServiceAlerts(ctx, service.ID, models.SeverityCritical); err != nil {
+			return err
+		}
+		if err := s.setImpactedBy(ctx, service.ID, nil); err != nil {
+			return err
+		}
+		if err := s.setAlertPending(ctx, service.ID, false); err != nil {
+			return err
+		}
+		return s.releaseDependents(ctx, service.ID)
+	}
+
+	return nil
+}
+
+// raiseDownAlert creates a critical alert for a service that went down. If
+// an upstream dependency is already down the service is only marked as
+// impacted by it; otherwise dependents that are already down have their
+// alerts suppressed in favour of this one. During maintenance the alert is
+// left pending until the window closes.
+func (s *HealthCheckService) raiseDownAlert(ctx context.Context, service *models.Service) error {
+	upstream, err := s.downUpstream(ctx, service.ID)
+	if err != nil {
+		return err
+	}
+	if upstream != nil {
+		log.Printf("Service %d (%s) is impacted by service %d, not alerting", service.ID, service.Name, *upstream)
+		return s.setImpactedBy(ctx, service.ID, upstream)
+	}
+
+	if err := s.suppressDependents(ctx, service.ID); err != nil {
+		return err
+	}
+
+	if alertPolicy(service.Config, models.SeverityCritical) == models.AlertPolicyNone {
+		return nil
+	}
+
+	window, err := s.maintenance.ActiveWindow(ctx, service.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		log.Printf("Service %d (%s) is in maintenance window %d (%s), not alerting", service.ID, service.Name, window.ID, window.Name)
+		return s.setAlertPending(ctx, service.ID, true)
+	}
+	if err := s.setAlertPending(ctx, service.ID, false); err != nil {
+		return err
+	}
+
+	// Does nothing if the service already has an active critical alert
+	_, err = s.alertService.CreateAlert(ctx, service.ID)
+	return err
+}
+
+func (s *HealthCheckService) setAlertPending(ctx context.Context, serviceID int64, pending bool) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE service_states SET alert_pending = $2 WHERE service_id = $1`, serviceID, pending)
+	if err != nil {
+		return fmt.Errorf("failed to update pending alert: %w", err)
+	}
+	return nil
+}
+
+const serviceStateQuery = `
+	SELECT state, consecutive_successes, consecutive_failures, last_status, last_checked_at, changed_at, impacted_by, alert_pending
+	FROM service_states
+	WHERE service_id = $1
+`
+
+// GetServiceState returns the persisted state for a service, or nil if it
+// has not been checked yet.
+func (s *HealthCheckService) GetServiceState(ctx context.Context, serviceID int64) (*models.ServiceState, error) {
+	state, err := scanServiceState(s.db.QueryRowContext(ctx, serviceStateQuery, serviceID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service state: %w", err)
+	}
+
+	return state, nil
+}
+
+func scanServiceState(row rowScanner) (*models.ServiceState, error) {
+	var state models.ServiceState
+	err := row.Scan(
+		&state.State,
+		&state.ConsecutiveSuccesses,
+		&state.ConsecutiveFailures,
+		&state.LastStatus,
+		&state.LastCheckedAt,
+		&state.ChangedAt,
+		&state.ImpactedBy,
+		&state.AlertPending,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func saveServiceState(ctx context.Context, tx *sql.Tx, serviceID int64, state *models.ServiceState) error {
+	query := `
+		UPDATE service_states
+		SET state = $2, consecutive_successes = $3, consecutive_failures = $4,
+			last_status = $5, last_checked_at = $6, changed_at = COALESCE($7, $6)
+		WHERE service_id = $1
+	`
+
+	var changedAt sql.NullTime
+	if !state.ChangedAt.IsZero() {
+		changedAt = sql.NullTime{Time: state.ChangedAt, Valid: true}
+	}
+
+	_, err := tx.ExecContext(ctx, query,
+		serviceID,
+		state.State,
+		state.ConsecutiveSuccesses,
+		state.ConsecutiveFailures,
+		state.LastStatus,
+		state.LastCheckedAt,
+		changedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save service state: %w", err)
+	}
+
+	return nil
+}
+
+// alertThreshold reads settings.alert_threshold, the number of consecutive
+// failures before a service is considered down.
+func (s *HealthCheckService) alertThreshold(ctx context.Context) int {
+	var threshold int
+	err := s.db.QueryRowContext(ctx, `SELECT alert_threshold FROM settings LIMIT 1`).Scan(&threshold)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to read alert threshold setting: %v", err)
+		}
+		return defaultFailureThreshold
+	}
+	if threshold <= 0 {
+		return defaultFailureThreshold
+	}
+
+	return threshold
+}
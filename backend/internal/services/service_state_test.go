@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+
+	"service-monitor/internal/models"
+)
+
+func TestNextState(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   models.ServiceState
+		healthy   bool
+		want      string
+		successes int
+		failures  int
+	}{
+		{"new service up", models.ServiceState{}, true, models.StateUp, 1, 0},
+		{"new service failing", models.ServiceState{}, false, models.StateSuspect, 0, 1},
+		{"up stays up", models.ServiceState{State: models.StateUp, ConsecutiveSuccesses: 4}, true, models.StateUp, 5, 0},
+		{"up turns suspect", models.ServiceState{State: models.StateUp, ConsecutiveSuccesses: 4}, false, models.StateSuspect, 0, 1},
+		{"suspect below threshold", models.ServiceState{State: models.StateSuspect, ConsecutiveFailures: 1}, false, models.StateSuspect, 0, 2},
+		{"suspect reaches threshold", models.ServiceState{State: models.StateSuspect, ConsecutiveFailures: 2}, false, models.StateDown, 0, 3},
+		{"suspect recovers at once", models.ServiceState{State: models.StateSuspect, ConsecutiveFailures: 2}, true, models.StateUp, 1, 0},
+		{"down stays down", models.ServiceState{State: models.StateDown, ConsecutiveFailures: 3}, false, models.StateDown, 0, 4},
+		{"down starts recovering", models.ServiceState{State: models.StateDown, ConsecutiveFailures: 3}, true, models.StateRecovering, 1, 0},
+		{"recovering below threshold", models.ServiceState{State: models.StateRecovering, ConsecutiveSuccesses: 1}, true, models.StateRecovering, 2, 0},
+		{"recovering reaches threshold", models.ServiceState{State: models.StateRecovering, ConsecutiveSuccesses: 2}, true, models.StateUp, 3, 0},
+		{"recovering fails back down", models.ServiceState{State: models.StateRecovering, ConsecutiveSuccesses: 2}, false, models.StateDown, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextState(tt.current, tt.healthy, 3, 3)
+			if got.State != tt.want {
+				t.Errorf("state = %q, want %q", got.State, tt.want)
+			}
+			if got.ConsecutiveSuccesses != tt.successes || got.ConsecutiveFailures != tt.failures {
+				t.Errorf("successes, failures = %d, %d, want %d, %d",
+					got.ConsecutiveSuccesses, got.ConsecutiveFailures, tt.successes, tt.failures)
+			}
+		})
+	}
+}
+
+func TestNextStateThresholdOfOne(t *testing.T) {
+	down := nextState(models.ServiceState{State: models.StateUp}, false, 1, 1)
+	if down.State != models.StateDown {
+		t.Errorf("failure with threshold 1: state = %q, want %q", down.State, models.StateDown)
+	}
+
+	up := nextState(down, true, 1, 1)
+	if up.State != models.StateUp {
+		t.Errorf("success with threshold 1: state = %q, want %q", up.State, models.StateUp)
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// maxBannerSize bounds how much of the server's response is read while
+// looking for the expected string.
+const maxBannerSize = 4096
+
+// probeTCP connects to the service's host:port and, when configured, sends
+// Config.SendString and waits for Config.ExpectString in the reply. The
+// recorded response time is the TCP connect latency.
+func probeTCP(ctx context.Context, service *models.Service) *CheckResult {
+	address, err := tcpAddress(service.URL)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(service))
+	defer cancel()
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+	defer conn.Close()
+
+	connectTime := time.Since(start).Milliseconds()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if service.Config.SendString != "" {
+		if _, err := conn.Write([]byte(service.Config.SendString)); err != nil {
+			return failedResult("down", connectTime, fmt.Sprintf("failed to send data: %v", err))
+		}
+	}
+
+	if service.Config.ExpectString != "" {
+		banner, err := readUntil(conn, service.Config.ExpectString)
+		if err != nil {
+			return failedResult("down", connectTime,
+				fmt.Sprintf("expected %q, got %q: %v", service.Config.ExpectString, banner, err))
+		}
+	}
+
+	return &CheckResult{Status: "up", ResponseTime: connectTime}
+}
+
+func validateTCP(service *models.Service) error {
+	_, err := tcpAddress(service.URL)
+	return err
+}
+
+// readUntil reads from conn until expect appears in the data received so far,
+// the read deadline passes, or maxBannerSize bytes have been read.
+func readUntil(conn net.Conn, expect string) (string, error) {
+	buf := make([]byte, 0, 512)
+	chunk := make([]byte, 512)
+
+	for len(buf) < maxBannerSize {
+		n, err := conn.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if strings.Contains(string(buf), expect) {
+			return string(buf), nil
+		}
+		if err != nil {
+			return string(buf), err
+		}
+	}
+
+	return string(buf), fmt.Errorf("expected string not found in first %d bytes", maxBannerSize)
+}
+
+// tcpAddress extracts host:port from a service URL. Both bare "host:port"
+// and URL forms such as "tcp://host:port" are accepted.
+func tcpAddress(rawURL string) (string, error) {
+	address := rawURL
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid service URL: %w", err)
+		}
+		address = u.Host
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid TCP address %q: %w", rawURL, err)
+	}
+	if host == "" || port == "" {
+		return "", fmt.Errorf("invalid TCP address %q: host and port are required", rawURL)
+	}
+
+	return address, nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+const defaultCertExpiryDays = 14
+
+// certificateInfo summarises the leaf certificate presented by a server.
+type certificateInfo struct {
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	SANs          []string  `json:"sans"`
+	NotBefore     time.Time `json:"not_before"`
+	NotAfter      time.Time `json:"not_after"`
+	DaysRemaining int       `json:"days_remaining"`
+	ChainValid    bool      `json:"chain_valid"`
+	ChainError    string    `json:"chain_error,omitempty"`
+}
+
+// probeTLS performs a TLS handshake with the service's host:port and checks
+// the presented certificate. Invalid or expired certificates mark the service
+// down; certificates expiring within Config.CertExpiryDays raise a warning.
+func probeTLS(ctx context.Context, service *models.Service) *CheckResult {
+	address, err := tcpAddress(service.URL)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+	host, _, _ := net.SplitHostPort(address)
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(service))
+	defer cancel()
+
+	tlsConfig, err := newTLSConfig(service.Config)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+	// Verification is done by inspectCertificate so that details are still
+	// recorded for invalid certificates
+	roots := tlsConfig.RootCAs
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.ServerName = host
+
+	start := time.Now()
+	dialer := &tls.Dialer{Config: tlsConfig}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+	defer conn.Close()
+	responseTime := time.Since(start).Milliseconds()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	result := certificateResult(state, host, roots, service.Config)
+	result.ResponseTime = responseTime
+
+	return result
+}
+
+func validateTLS(service *models.Service) error {
+	if _, err := tcpAddress(service.URL); err != nil {
+		return err
+	}
+	_, err := newTLSConfig(service.Config)
+	return err
+}
+
+// certificateResult turns a TLS connection state into a check result. It is
+// shared by the TLS checker and HTTPS checks.
+func certificateResult(state tls.ConnectionState, host string, roots *x509.CertPool, cfg models.ServiceConfig) *CheckResult {
+	info, err := inspectCertificate(state, host, roots)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+
+	result := &CheckResult{
+		Status:  "up",
+		Details: models.CheckDetails{"certificate": info},
+	}
+
+	warnDays := cfg.CertExpiryDays
+	if warnDays <= 0 {
+		warnDays = defaultCertExpiryDays
+	}
+
+	switch {
+	case !info.ChainValid && !cfg.TLSSkipVerify:
+		result.Status = "down"
+		result.Error = fmt.Sprintf("invalid certificate: %s", info.ChainError)
+	case info.DaysRemaining < 0:
+		result.Status = "down"
+		result.Error = fmt.Sprintf("certificate expired on %s", info.NotAfter.Format(time.RFC3339))
+	case info.DaysRemaining <= warnDays:
+		result.Warning = fmt.Sprintf("certificate for %s expires in %d days (%s)",
+			host, info.DaysRemaining, info.NotAfter.Format("2006-01-02"))
+	}
+
+	return result
+}
+
+// inspectCertificate describes the leaf certificate and verifies the chain
+// against roots (the system pool when nil) for host.
+func inspectCertificate(state tls.ConnectionState, host string, roots *x509.CertPool) (*certificateInfo, error) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("server presented no certificate")
+	}
+	leaf := state.PeerCertificates[0]
+
+	sans := append([]string{}, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	info := &certificateInfo{
+		Subject:       leaf.Subject.String(),
+		Issuer:        leaf.Issuer.String(),
+		SANs:          sans,
+		NotBefore:     leaf.NotBefore,
+		NotAfter:      leaf.NotAfter,
+		DaysRemaining: int(math.Floor(time.Until(leaf.NotAfter).Hours() / 24)),
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	info.ChainValid = err == nil
+	if err != nil {
+		info.ChainError = err.Error()
+	}
+
+	return info, nil
+}
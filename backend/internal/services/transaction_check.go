@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+var (
+	templateVar  = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// stepResult describes one transaction step for health check details. URL
+// is the step's template and Extracted lists variable names only, as
+// extracted values are often credentials.
+type stepResult struct {
+	Name         string            `json:"name,omitempty"`
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	StatusCode   int               `json:"status_code,omitempty"`
+	ResponseTime int64             `json:"response_time"`
+	Passed       bool              `json:"passed"`
+	Error        string            `json:"error,omitempty"`
+	Assertions   []assertionResult `json:"assertions,omitempty"`
+	Extracted    []string          `json:"extracted,omitempty"`
+}
+
+// probeTransaction runs Config.Steps in order with a shared cookie jar,
+// stopping at the first step whose status, assertions or extractions fail.
+// Config.Headers are sent with every step and Config.Timeout bounds the
+// whole transaction. The response time is the total across steps.
+func probeTransaction(ctx context.Context, service *models.Service) *CheckResult {
+	client, err := newHTTPClient(service)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return failedResult("down", 0, err.Error())
+	}
+	client.Jar = jar
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(service))
+	defer cancel()
+
+	vars := make(map[string]string)
+	steps := make([]stepResult, 0, len(service.Config.Steps))
+	result := &CheckResult{Status: "up"}
+
+	for i, step := range service.Config.Steps {
+		sr := runStep(ctx, client, service, step, vars)
+		steps = append(steps, *sr)
+		result.ResponseTime += sr.ResponseTime
+
+		if !sr.Passed {
+			result.Status = "down"
+			result.Error = fmt.Sprintf("%s failed: %s", stepName(step, i), sr.Error)
+			result.Details = models.CheckDetails{
+				"steps":       steps,
+				"failed_step": i + 1,
+			}
+			return result
+		}
+	}
+
+	result.Details = models.CheckDetails{"steps": steps}
+	return result
+}
+
+func runStep(ctx context.Context, client *http.Client, service *models.Service, step models.TransactionStep, vars map[string]string) *stepResult {
+	method := strings.ToUpper(step.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	sr := &stepResult{Name: step.Name, Method: method, URL: step.URL}
+
+	fail := func(format string, args ...interface{}) *stepResult {
+		sr.Passed = false
+		sr.Error = fmt.Sprintf(format, args...)
+		return sr
+	}
+
+	rawURL, err := renderTemplate(step.URL, vars)
+	if err != nil {
+		return fail("url: %v", err)
+	}
+	target, err := stepURL(service.URL, rawURL)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	body, err := renderTemplate(step.Body, vars)
+	if err != nil {
+		return fail("body: %v", err)
+	}
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reqBody)
+	if err != nil {
+		return fail("invalid request: %s", requestError(step, vars, err))
+	}
+
+	headers := make(map[string]string, len(service.Config.Headers)+len(step.Headers))
+	for name, value := range service.Config.Headers {
+		headers[name] = value
+	}
+	for name, value := range step.Headers {
+		headers[name] = value
+	}
+	for name, value := range headers {
+		value, err := renderTemplate(value, vars)
+		if err != nil {
+			return fail("header %s: %v", name, err)
+		}
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "service-monitor")
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		sr.ResponseTime = time.Since(start).Milliseconds()
+		return fail("%s", requestError(step, vars, err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	sr.ResponseTime = time.Since(start).Milliseconds()
+	sr.StatusCode = resp.StatusCode
+	if err != nil {
+		return fail("failed to read response body: %v", err)
+	}
+
+	ok, err := statusAccepted(resp.StatusCode, models.ServiceConfig{
+		ExpectedStatus:   step.ExpectedStatus,
+		AcceptedStatuses: step.AcceptedStatuses,
+	})
+	if err != nil {
+		return fail("%v", err)
+	}
+	if !ok {
+		return fail("HTTP %d", resp.StatusCode)
+	}
+
+	if len(step.Assertions) > 0 {
+		results, failures := evaluateAssertions(step.Assertions, &httpResponse{
+			Header:       resp.Header,
+			Body:         respBody,
+			ResponseTime: sr.ResponseTime,
+		})
+		sr.Assertions = results
+		if len(failures) > 0 {
+			return fail("assertion failed: %s", strings.Join(failures, "; "))
+		}
+	}
+
+	var document interface{}
+	parsed := false
+	for _, extraction := range step.Extract {
+		value, err := extract(extraction, resp, respBody, client.Jar, &document, &parsed)
+		if err != nil {
+			return fail("extract %s: %v", extraction.Name, err)
+		}
+		vars[extraction.Name] = value
+		sr.Extracted = append(sr.Extracted, extraction.Name)
+	}
+
+	sr.Passed = true
+	return sr
+}
+
+// requestError describes a failed request by the step's URL template
+// instead of the rendered URL net/http reports, and masks extracted values
+// that still appear in the cause, such as a host in a DNS error.
+func requestError(step models.TransactionStep, vars map[string]string, err error) string {
+	message := err.Error()
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		message = fmt.Sprintf("%s %q: %v", urlErr.Op, step.URL, urlErr.Err)
+	}
+	for name, value := range vars {
+		if value != "" {
+			message = strings.ReplaceAll(message, value, "{{"+name+"}}")
+		}
+	}
+	return message
+}
+
+// extract pulls one value out of a step's response. The body is decoded as
+// JSON at most once per step.
+func extract(extraction models.Extraction, resp *http.Response, body []byte, jar http.CookieJar, document *interface{}, parsed *bool) (string, error) {
+	switch extraction.Source {
+	case models.ExtractJSONPath:
+		if !*parsed {
+			if err := json.Unmarshal(body, document); err != nil {
+				return "", fmt.Errorf("body is not valid JSON: %v", err)
+			}
+			*parsed = true
+		}
+		value, err := evalJSONPath(*document, extraction.Path)
+		if err != nil {
+			return "", err
+		}
+		return jsonString(value), nil
+	case models.ExtractHeader:
+		value := resp.Header.Get(extraction.Path)
+		if value == "" {
+			return "", fmt.Errorf("header %s is missing", extraction.Path)
+		}
+		return value, nil
+	case models.ExtractRegex:
+		re, err := regexp.Compile(extraction.Path)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex %q: %v", extraction.Path, err)
+		}
+		match := re.FindSubmatch(body)
+		if match == nil {
+			return "", fmt.Errorf("body does not match /%s/", extraction.Path)
+		}
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+		return string(match[0]), nil
+	case models.ExtractCookie:
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == extraction.Path {
+				return cookie.Value, nil
+			}
+		}
+		for _, cookie := range jar.Cookies(resp.Request.URL) {
+			if cookie.Name == extraction.Path {
+				return cookie.Value, nil
+			}
+		}
+		return "", fmt.Errorf("cookie %s is not set", extraction.Path)
+	}
+
+	return "", fmt.Errorf("unknown extraction source %q", extraction.Source)
+}
+
+func validateTransaction(service *models.Service) error {
+	if len(service.Config.Steps) == 0 {
+		return fmt.Errorf("transaction requires at least one step")
+	}
+	if _, err := newTLSConfig(service.Config); err != nil {
+		return err
+	}
+
+	defined := make(map[string]bool)
+	for i, step := range service.Config.Steps {
+		name := stepName(step, i)
+
+		for _, value := range stepTemplates(service.Config, step) {
+			for _, match := range templateVar.FindAllStringSubmatch(value, -1) {
+				if !defined[match[1]] {
+					return fmt.Errorf("%s uses {{%s}} before it is extracted", name, match[1])
+				}
+			}
+		}
+
+		if !templateVar.MatchString(step.URL) {
+			if _, err := stepURL(service.URL, step.URL); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		for _, pattern := range step.AcceptedStatuses {
+			if _, err := matchStatus(0, pattern); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		if err := validateAssertions(step.Assertions); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		for _, extraction := range step.Extract {
+			if !variableName.MatchString(extraction.Name) {
+				return fmt.Errorf("%s: invalid variable name %q", name, extraction.Name)
+			}
+			switch extraction.Source {
+			case models.ExtractJSONPath:
+				if _, err := parseJSONPath(extraction.Path); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			case models.ExtractRegex:
+				if _, err := regexp.Compile(extraction.Path); err != nil {
+					return fmt.Errorf("%s: invalid regex %q: %v", name, extraction.Path, err)
+				}
+			case models.ExtractHeader, models.ExtractCookie:
+				if extraction.Path == "" {
+					return fmt.Errorf("%s: %s extraction requires a name in path", name, extraction.Source)
+				}
+			default:
+				return fmt.Errorf("%s: unknown extraction source %q", name, extraction.Source)
+			}
+			defined[extraction.Name] = true
+		}
+	}
+
+	return nil
+}
+
+// stepURL resolves a step URL against the service URL, which may be empty
+// when every step uses an absolute URL.
+func stepURL(base, ref string) (*url.URL, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if !u.IsAbs() {
+		baseURL, err := url.Parse(base)
+		if err != nil || !baseURL.IsAbs() {
+			return nil, fmt.Errorf("relative URL %q requires an absolute service URL", ref)
+		}
+		u = baseURL.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("URL must start with http:// or https://")
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("URL must include a host")
+	}
+
+	return u, nil
+}
+
+// renderTemplate replaces {{name}} with extracted values.
+func renderTemplate(s string, vars map[string]string) (string, error) {
+	var missing string
+	out := templateVar.ReplaceAllStringFunc(s, func(match string) string {
+		name := templateVar.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("undefined variable %q", missing)
+	}
+
+	return out, nil
+}
+
+// stepTemplates returns the values a step renders: its URL, body and
+// headers, including the service's headers that it does not override.
+func stepTemplates(cfg models.ServiceConfig, step models.TransactionStep) []string {
+	values := []string{step.URL, step.Body}
+	for name, value := range cfg.Headers {
+		if _, ok := step.Headers[name]; !ok {
+			values = append(values, value)
+		}
+	}
+	for _, value := range step.Headers {
+		values = append(values, value)
+	}
+	return values
+}
+
+// stepName labels a step in errors as "step 2 (login)", or "step 2" when it
+// has no name.
+func stepName(step models.TransactionStep, index int) string {
+	if step.Name != "" {
+		return fmt.Sprintf("step %d (%s)", index+1, step.Name)
+	}
+	return fmt.Sprintf("step %d", index+1)
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"service-monitor/internal/models"
+)
+
+func TestProbeTransactionKeepsExtractedValuesOutOfErrors(t *testing.T) {
+	const secret = "s3cr3t-session-token"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token":"` + secret + `"}`))
+	})
+	mux.HandleFunc("/account/", func(w http.ResponseWriter, r *http.Request) {
+		// Drop the connection so the request itself fails
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		step models.TransactionStep
+	}{
+		{"in query", models.TransactionStep{Name: "account", URL: "/account/?token={{token}}"}},
+		{"in path", models.TransactionStep{Name: "account", URL: "/account/{{token}}"}},
+		{"in host", models.TransactionStep{Name: "account", URL: "http://{{token}}.invalid/account"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &models.Service{
+				URL: server.URL,
+				Config: models.ServiceConfig{
+					Steps: []models.TransactionStep{
+						{
+							Name:    "login",
+							Method:  http.MethodPost,
+							URL:     "/login",
+							Extract: []models.Extraction{{Name: "token", Source: models.ExtractJSONPath, Path: "$.token"}},
+						},
+						tt.step,
+					},
+				},
+			}
+
+			result := probeTransaction(context.Background(), service)
+			if result.Status != "down" {
+				t.Fatalf("status = %q, want down", result.Status)
+			}
+			if result.Error == "" {
+				t.Fatal("expected an error")
+			}
+			if strings.Contains(result.Error, secret) {
+				t.Errorf("check error contains the extracted token: %s", result.Error)
+			}
+
+			steps, ok := result.Details["steps"].([]stepResult)
+			if !ok || len(steps) != 2 {
+				t.Fatalf("steps = %#v, want 2 step results", result.Details["steps"])
+			}
+			for _, step := range steps {
+				if strings.Contains(step.Error, secret) || strings.Contains(step.URL, secret) {
+					t.Errorf("step %q exposes the extracted token: url %q, error %q", step.Name, step.URL, step.Error)
+				}
+			}
+			if !strings.Contains(steps[1].Error, tt.step.URL) {
+				t.Errorf("step error %q does not name the URL template %q", steps[1].Error, tt.step.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+// SLOPeriod is the rolling window error budgets are measured over.
+const SLOPeriod = 30 * 24 * time.Hour
+
+// GetUptime computes the service's availability over [from, to) from its
+// checks and critical alerts, and the error budget over the SLO period
+// ending at to. With excludeMaintenance, checks recorded during maintenance
+// and alert time overlapping maintenance windows are left out.
+func (s *HealthCheckService) GetUptime(ctx context.Context, service *models.Service, from, to time.Time, excludeMaintenance bool) (*models.Uptime, error) {
+	uptime := &models.Uptime{
+		ServiceID:           service.ID,
+		From:                from,
+		To:                  to,
+		ExcludesMaintenance: excludeMaintenance,
+	}
+
+	if err := s.countChecks(ctx, uptime); err != nil {
+		return nil, err
+	}
+
+	var maintenance []models.MaintenancePeriod
+	if excludeMaintenance {
+		var err error
+		maintenance, err = s.maintenance.Periods(ctx, service.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.sumAlertDowntime(ctx, uptime, maintenance); err != nil {
+		return nil, err
+	}
+
+	measured := uptime.UpChecks + uptime.DownChecks
+	if measured > 0 {
+		availability := 100 * float64(uptime.UpChecks) / float64(measured)
+		uptime.Availability = &availability
+	}
+
+	if target := service.Config.SLOTarget; target > 0 {
+		period := &models.Uptime{
+			ServiceID:           service.ID,
+			From:                to.Add(-SLOPeriod),
+			To:                  to,
+			ExcludesMaintenance: excludeMaintenance,
+		}
+		if err := s.countChecks(ctx, period); err != nil {
+			return nil, err
+		}
+
+		uptime.SLOTarget = target
+		uptime.ErrorBudget = errorBudget(target, period.UpChecks, period.DownChecks)
+		uptime.ErrorBudget.PeriodFrom = period.From
+		uptime.ErrorBudget.PeriodTo = period.To
+	}
+
+	return uptime, nil
+}
+
+// countChecks tallies the service's checks in the range by outcome. Where
+// raw checks have been deleted it reads rollups instead, counting only the
+// rolled up periods that start within the range.
+func (s *HealthCheckService) countChecks(ctx context.Context, uptime *models.Uptime) error {
+	state, err := loadRollupState(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	from, to := uptime.From, uptime.To
+
+	rawStart := from
+	if state.rawFrom.After(from) {
+		rawStart = state.rawFrom
+	}
+	if err := s.countRawChecks(ctx, uptime, rawStart, to); err != nil {
+		return err
+	}
+	if !from.Before(state.rawFrom) {
+		return nil
+	}
+
+	hourlyStart := from
+	if state.hourlyFrom.After(from) {
+		hourlyStart = state.hourlyFrom
+	}
+	if err := s.countRolledUpChecks(ctx, uptime, hourlyRollup, hourlyStart, earliest(to, state.rawFrom)); err != nil {
+		return err
+	}
+	if !from.Before(state.hourlyFrom) {
+		return nil
+	}
+
+	return s.countRolledUpChecks(ctx, uptime, dailyRollup, from, earliest(to, state.hourlyFrom))
+}
+
+func (s *HealthCheckService) countRawChecks(ctx context.Context, uptime *models.Uptime, from, to time.Time) error {
+	if !from.Before(to) {
+		return nil
+	}
+
+	query := `
+		SELECT status, in_maintenance, COUNT(*)
+		FROM health_checks
+		WHERE service_id = $1 AND checked_at >= $2 AND checked_at < $3
+		GROUP BY status, in_maintenance
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, uptime.ServiceID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to count health checks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var inMaintenance bool
+		var count int
+		if err := rows.Scan(&status, &inMaintenance, &count); err != nil {
+			return fmt.Errorf("failed to scan health check count: %w", err)
+		}
+		addChecks(uptime, status, inMaintenance, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating health check counts: %w", err)
+	}
+
+	return nil
+}
+
+func (s *HealthCheckService) countRolledUpChecks(ctx context.Context, uptime *models.Uptime, level rollupLevel, from, to time.Time) error {
+	if !from.Before(to) {
+		return nil
+	}
+
+	query := `
+		SELECT in_maintenance, SUM(up), SUM(degraded), SUM(down), SUM(unknown)
+		FROM ` + level.table + `
+		WHERE service_id = $1 AND bucket >= $2 AND bucket < $3
+		GROUP BY in_maintenance
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, uptime.ServiceID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to count health checks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var inMaintenance bool
+		var up, degraded, down, unknown int
+		if err := rows.Scan(&inMaintenance, &up, &degraded, &down, &unknown); err != nil {
+			return fmt.Errorf("failed to scan health check count: %w", err)
+		}
+		addChecks(uptime, "up", inMaintenance, up)
+		addChecks(uptime, "degraded", inMaintenance, degraded)
+		addChecks(uptime, "down", inMaintenance, down)
+		addChecks(uptime, "unknown", inMaintenance, unknown)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating health check counts: %w", err)
+	}
+
+	return nil
+}
+
+func addChecks(uptime *models.Uptime, status string, inMaintenance bool, count int) {
+	uptime.TotalChecks += count
+	switch {
+	case inMaintenance && uptime.ExcludesMaintenance:
+		uptime.MaintenanceChecks += count
+	case status == "up" || status == "degraded":
+		uptime.UpChecks += count
+	case status == "down":
+		uptime.DownChecks += count
+	default:
+		uptime.UnknownChecks += count
+	}
+}
+
+// sumAlertDowntime counts the critical alerts overlapping the range and the
+// time they were open within it, less any overlap with maintenance.
+func (s *HealthCheckService) sumAlertDowntime(ctx context.Context, uptime *models.Uptime, maintenance []models.MaintenancePeriod) error {
+	query := `
+		SELECT started_at, COALESCE(resolved_at, CURRENT_TIMESTAMP)
+		FROM alerts
+		WHERE service_id = $1 AND severity = 'critical'
+		  AND started_at < $3 AND (resolved_at IS NULL OR resolved_at > $2)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, uptime.ServiceID, uptime.From, uptime.To)
+	if err != nil {
+		return fmt.Errorf("failed to get alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var downtime time.Duration
+	for rows.Next() {
+		var start, end time.Time
+		if err := rows.Scan(&start, &end); err != nil {
+			return fmt.Errorf("failed to scan alert: %w", err)
+		}
+		if start.Before(uptime.From) {
+			start = uptime.From
+		}
+		if end.After(uptime.To) {
+			end = uptime.To
+		}
+		if !start.Before(end) {
+			continue
+		}
+
+		uptime.Incidents++
+		downtime += end.Sub(start) - overlap(start, end, maintenance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating alerts: %w", err)
+	}
+
+	uptime.AlertDowntime = downtime.Seconds()
+	return nil
+}
+
+// overlap returns how much of [start, end) falls within the periods, which
+// are ordered by start and may overlap each other.
+func overlap(start, end time.Time, periods []models.MaintenancePeriod) time.Duration {
+	var total time.Duration
+	covered := start
+	for _, p := range periods {
+		from, to := p.Start, p.End
+		if from.Before(covered) {
+			from = covered
+		}
+		if to.After(end) {
+			to = end
+		}
+		if from.Before(to) {
+			total += to.Sub(from)
+			covered = to
+		}
+	}
+	return total
+}
+
+// errorBudget compares the failed checks in an SLO period with those allowed
+// by an SLO target in percent.
+func errorBudget(target float64, up, down int) *models.ErrorBudget {
+	measured := up + down
+	allowedRate := 1 - target/100
+
+	budget := &models.ErrorBudget{
+		Allowed:   allowedRate * float64(measured),
+		Consumed:  down,
+		Remaining: 1,
+	}
+	if measured == 0 {
+		return budget
+	}
+
+	failureRate := float64(down) / float64(measured)
+	budget.BurnRate = failureRate / allowedRate
+	budget.Remaining = math.Max(0, 1-budget.BurnRate)
+
+	return budget
+}
+
+func validateSLO(service *models.Service) error {
+	target := service.Config.SLOTarget
+	if target < 0 || target >= 100 {
+		return fmt.Errorf("sloTarget must be a percentage below 100")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"service-monitor/internal/models"
+)
+
+func TestErrorBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    float64
+		up, down  int
+		allowed   float64
+		remaining float64
+		burnRate  float64
+	}{
+		{"no checks", 99.9, 0, 0, 0, 1, 0},
+		{"no failures", 99, 1000, 0, 10, 1, 0},
+		{"half consumed", 99, 995, 5, 10, 0.5, 0.5},
+		{"exactly consumed", 99, 990, 10, 10, 0, 1},
+		{"overspent is clamped", 99, 980, 20, 10, 0, 2},
+		{"all failing", 90, 0, 10, 1, 0, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := errorBudget(tt.target, tt.up, tt.down)
+			if budget.Consumed != tt.down {
+				t.Errorf("consumed = %d, want %d", budget.Consumed, tt.down)
+			}
+			if !approx(budget.Allowed, tt.allowed) {
+				t.Errorf("allowed = %v, want %v", budget.Allowed, tt.allowed)
+			}
+			if !approx(budget.Remaining, tt.remaining) {
+				t.Errorf("remaining = %v, want %v", budget.Remaining, tt.remaining)
+			}
+			if !approx(budget.BurnRate, tt.burnRate) {
+				t.Errorf("burn rate = %v, want %v", budget.BurnRate, tt.burnRate)
+			}
+		})
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	period := func(from, to int) models.MaintenancePeriod {
+		return models.MaintenancePeriod{Start: at(from), End: at(to)}
+	}
+
+	tests := []struct {
+		name    string
+		periods []models.MaintenancePeriod
+		want    time.Duration
+	}{
+		{"no periods", nil, 0},
+		{"before range", []models.MaintenancePeriod{period(0, 10)}, 0},
+		{"after range", []models.MaintenancePeriod{period(70, 80)}, 0},
+		{"touching range", []models.MaintenancePeriod{period(0, 10), period(60, 70)}, 0},
+		{"inside range", []models.MaintenancePeriod{period(20, 30)}, 10 * time.Minute},
+		{"covers range", []models.MaintenancePeriod{period(0, 90)}, 50 * time.Minute},
+		{"overlaps start", []models.MaintenancePeriod{period(0, 15)}, 5 * time.Minute},
+		{"overlaps end", []models.MaintenancePeriod{period(55, 90)}, 5 * time.Minute},
+		{"separate periods", []models.MaintenancePeriod{period(15, 20), period(40, 45)}, 10 * time.Minute},
+		{"overlapping periods", []models.MaintenancePeriod{period(15, 30), period(25, 40)}, 25 * time.Minute},
+		{"nested periods", []models.MaintenancePeriod{period(15, 40), period(20, 30)}, 25 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := overlap(at(10), at(60), tt.periods)
+			if got != tt.want {
+				t.Errorf("overlap = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	workerPollInterval    = time.Second
+	workerRequeueInterval = 10 * time.Second
+)
+
+// Worker pulls checks from a CheckQueue and runs them. Several worker
+// processes can share one queue; each runs up to concurrency checks at once.
+type Worker struct {
+	queue              *CheckQueue
+	serviceService     *ServiceService
+	healthCheckService *HealthCheckService
+	concurrency        int
+}
+
+func NewWorker(queue *CheckQueue, serviceService *ServiceService, healthCheckService *HealthCheckService, concurrency int) *Worker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Worker{
+		queue:              queue,
+		serviceService:     serviceService,
+		healthCheckService: healthCheckService,
+		concurrency:        concurrency,
+	}
+}
+
+// Run processes checks until ctx is cancelled and in-flight checks finish.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.requeueExpired(ctx)
+	}()
+
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.process(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (w *Worker) process(ctx context.Context) {
+	for ctx.Err() == nil {
+		serviceID, ok, err := w.queue.Dequeue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Worker failed to dequeue check: %v", err)
+		}
+		if !ok {
+			select {
+			case <-ctx.Done():
+			case <-time.After(workerPollInterval):
+			}
+			continue
+		}
+
+		w.runCheck(ctx, serviceID)
+	}
+}
+
+// runCheck loads the service fresh from the database, so edits and deletions
+// since the check was queued are respected, and keeps its lease alive while
+// the check runs. The job is only completed once the check is recorded; if
+// this process dies first, the lease expires and another worker retries it.
+func (w *Worker) runCheck(ctx context.Context, serviceID int64) {
+	leaseCtx, stopLease := context.WithCancel(ctx)
+	defer stopLease()
+	go w.renewLease(leaseCtx, serviceID)
+
+	service, err := w.serviceService.GetService(ctx, serviceID)
+	if err != nil {
+		if err.Error() != "service not found" {
+			log.Printf("Worker failed to load service %d: %v", serviceID, err)
+			return
+		}
+	} else {
+		check, err := w.healthCheckService.CheckService(ctx, service)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Health check for service %d failed: %v", serviceID, err)
+			}
+			// Once the check is recorded, running it again would only
+			// record a duplicate
+			if check == nil {
+				return
+			}
+		}
+		if check.Status != "up" {
+			log.Printf("Service %d (%s) is %s: %s", service.ID, service.Name, check.Status, check.Error)
+		}
+	}
+
+	stopLease()
+	if err := w.queue.Complete(context.Background(), serviceID); err != nil {
+		log.Printf("Worker failed to complete check for service %d: %v", serviceID, err)
+	}
+}
+
+func (w *Worker) renewLease(ctx context.Context, serviceID int64) {
+	ticker := time.NewTicker(w.queue.Lease() / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.queue.ExtendLease(ctx, serviceID); err != nil && ctx.Err() == nil {
+				log.Printf("Worker failed to extend lease for service %d: %v", serviceID, err)
+			}
+		}
+	}
+}
+
+// requeueExpired periodically recovers checks leased by workers that died.
+func (w *Worker) requeueExpired(ctx context.Context) {
+	ticker := time.NewTicker(workerRequeueInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := w.queue.RequeueExpired(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Worker failed to requeue expired checks: %v", err)
+				}
+				continue
+			}
+			if count > 0 {
+				log.Printf("Requeued %d checks with expired leases", count)
+			}
+		}
+	}
+}
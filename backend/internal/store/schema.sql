@@ -4,6 +4,7 @@ CREATE TABLE IF NOT EXISTS services (
     type VARCHAR(50) NOT NULL,
     url VARCHAR(255) NOT NULL,
     config JSONB NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -36,16 +37,109 @@ CREATE TABLE IF NOT EXISTS health_checks (
     status VARCHAR(50) NOT NULL,
     response_time BIGINT NOT NULL, -- in milliseconds
     error TEXT,
+    details JSONB,
+    timing JSONB,
+    in_maintenance BOOLEAN NOT NULL DEFAULT false,
     checked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS service_states (
+    service_id INTEGER PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+    state VARCHAR(50) NOT NULL,
+    consecutive_successes INTEGER NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_status VARCHAR(50) NOT NULL,
+    last_checked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    impacted_by INTEGER REFERENCES services(id) ON DELETE SET NULL,
+    alert_pending BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS service_dependencies (
+    service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    depends_on_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (service_id, depends_on_id),
+    CHECK (service_id <> depends_on_id)
+);
+
+CREATE TABLE IF NOT EXISTS health_check_rollups_hourly (
+    service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    in_maintenance BOOLEAN NOT NULL,
+    count INTEGER NOT NULL,
+    up INTEGER NOT NULL,
+    degraded INTEGER NOT NULL,
+    down INTEGER NOT NULL,
+    unknown INTEGER NOT NULL,
+    response_time_sum BIGINT NOT NULL,
+    p50 DOUBLE PRECISION,
+    p95 DOUBLE PRECISION,
+    p99 DOUBLE PRECISION,
+    PRIMARY KEY (service_id, bucket, in_maintenance)
+);
+
+CREATE TABLE IF NOT EXISTS health_check_rollups_daily (
+    service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    in_maintenance BOOLEAN NOT NULL,
+    count INTEGER NOT NULL,
+    up INTEGER NOT NULL,
+    degraded INTEGER NOT NULL,
+    down INTEGER NOT NULL,
+    unknown INTEGER NOT NULL,
+    response_time_sum BIGINT NOT NULL,
+    p50 DOUBLE PRECISION,
+    p95 DOUBLE PRECISION,
+    p99 DOUBLE PRECISION,
+    PRIMARY KEY (service_id, bucket, in_maintenance)
+);
+
+CREATE TABLE IF NOT EXISTS health_check_rollup_state (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    hourly_rolled_to TIMESTAMP WITH TIME ZONE,
+    daily_rolled_to TIMESTAMP WITH TIME ZONE,
+    raw_from TIMESTAMP WITH TIME ZONE,
+    hourly_from TIMESTAMP WITH TIME ZONE
+);
+
+INSERT INTO health_check_rollup_state (id) VALUES (true) ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    schedule VARCHAR(255) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    duration INTEGER NOT NULL DEFAULT 0, -- in minutes
+    service_ids INTEGER[] NOT NULL DEFAULT '{}',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS heartbeats (
+    service_id INTEGER PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    last_ping_at TIMESTAMP WITH TIME ZONE,
+    last_ping_status VARCHAR(50),
+    last_error TEXT NOT NULL DEFAULT '',
+    last_start_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS alerts (
     id SERIAL PRIMARY KEY,
     service_id INTEGER REFERENCES services(id) ON DELETE CASCADE,
     status VARCHAR(50) NOT NULL,
+    severity VARCHAR(50) NOT NULL DEFAULT 'critical',
+    message TEXT NOT NULL DEFAULT '',
     started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     resolved_at TIMESTAMP WITH TIME ZONE,
     verification_status VARCHAR(50) NOT NULL,
+    escalation_token BIGINT,
+    escalation_level INTEGER NOT NULL DEFAULT 0,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -78,9 +172,13 @@ CREATE TABLE IF NOT EXISTS settings (
 CREATE INDEX idx_services_type ON services(type);
 CREATE INDEX idx_health_checks_service_id ON health_checks(service_id);
 CREATE INDEX idx_health_checks_checked_at ON health_checks(checked_at);
+CREATE INDEX idx_health_checks_service_id_checked_at ON health_checks(service_id, checked_at);
 CREATE INDEX idx_alerts_service_id ON alerts(service_id);
 CREATE INDEX idx_alerts_status ON alerts(status);
+CREATE INDEX idx_alerts_service_id_severity ON alerts(service_id, severity) WHERE status = 'active';
 CREATE INDEX idx_alert_notifications_alert_id ON alert_notifications(alert_id);
 CREATE INDEX idx_alert_notifications_user_id ON alert_notifications(user_id);
 CREATE INDEX idx_escalation_chains_service_id ON escalation_chains(service_id);
-CREATE INDEX idx_escalation_chains_user_id ON escalation_chains(user_id); 
\ No newline at end of file
+CREATE INDEX idx_escalation_chains_user_id ON escalation_chains(user_id);
+CREATE INDEX idx_service_dependencies_depends_on_id ON service_dependencies(depends_on_id);
+CREATE UNIQUE INDEX idx_alerts_unresolved_service_id_severity ON alerts(service_id, severity) WHERE resolved_at IS NULL;
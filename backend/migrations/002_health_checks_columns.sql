@@ -0,0 +1,27 @@
+-- Align health_checks with the columns used by HealthCheckService
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'health_checks' AND column_name = 'error_message'
+    ) THEN
+        ALTER TABLE health_checks RENAME COLUMN error_message TO error;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'health_checks' AND column_name = 'created_at'
+    ) THEN
+        ALTER TABLE health_checks RENAME COLUMN created_at TO checked_at;
+    END IF;
+END $$;
+
+ALTER TABLE health_checks ALTER COLUMN response_time TYPE BIGINT;
+
+-- Remove a service's health history together with the service
+ALTER TABLE health_checks DROP CONSTRAINT IF EXISTS health_checks_service_id_fkey;
+ALTER TABLE health_checks
+    ADD CONSTRAINT health_checks_service_id_fkey
+    FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_health_checks_service_id_checked_at ON health_checks(service_id, checked_at);
@@ -0,0 +1,2 @@
+-- Checker-specific measurements (ping statistics, timings, ...)
+ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS details JSONB;
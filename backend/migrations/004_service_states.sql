@@ -0,0 +1,39 @@
+-- Current state of each service's up/suspect/down/recovering state machine
+CREATE TABLE IF NOT EXISTS service_states (
+    service_id INTEGER PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+    state VARCHAR(50) NOT NULL,
+    consecutive_successes INTEGER NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_status VARCHAR(50) NOT NULL,
+    last_checked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Alerts are now created by the state machine; give them the defaults and
+-- cascade behaviour the alert service relies on
+ALTER TABLE alerts ALTER COLUMN started_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE alerts DROP CONSTRAINT IF EXISTS alerts_service_id_fkey;
+ALTER TABLE alerts
+    ADD CONSTRAINT alerts_service_id_fkey
+    FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS escalation_chains (
+    id SERIAL PRIMARY KEY,
+    service_id INTEGER REFERENCES services(id) ON DELETE CASCADE,
+    level INTEGER NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    wait_time INTEGER NOT NULL, -- in minutes
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(service_id, level)
+);
+
+CREATE TABLE IF NOT EXISTS alert_notifications (
+    id SERIAL PRIMARY KEY,
+    alert_id INTEGER REFERENCES alerts(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP WITH TIME ZONE
+);
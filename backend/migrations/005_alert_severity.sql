@@ -0,0 +1,5 @@
+-- Alert severity and an optional message used in notifications
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS severity VARCHAR(50) NOT NULL DEFAULT 'critical';
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS message TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_alerts_service_id_severity ON alerts(service_id, severity) WHERE status = 'active';
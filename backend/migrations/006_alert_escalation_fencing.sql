@@ -0,0 +1,5 @@
+-- Escalations run on the elected leader. escalation_token is the fencing
+-- token of the leader escalating an alert and escalation_level the level it
+-- reached, so a new leader can take over where the old one stopped.
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS escalation_token BIGINT;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS escalation_level INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Heartbeat services are pinged by the jobs they monitor at /api/ping/:token
+CREATE TABLE IF NOT EXISTS heartbeats (
+    service_id INTEGER PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    last_ping_at TIMESTAMP WITH TIME ZONE,
+    last_ping_status VARCHAR(50),
+    last_error TEXT NOT NULL DEFAULT '',
+    last_start_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Phase breakdown of HTTP checks (DNS, connect, TLS, TTFB, transfer) in ms
+ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS timing JSONB;
@@ -0,0 +1,13 @@
+-- Services can depend on other services. A down service whose upstream is
+-- already down is marked impacted by it instead of raising its own alert.
+CREATE TABLE IF NOT EXISTS service_dependencies (
+    service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    depends_on_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (service_id, depends_on_id),
+    CHECK (service_id <> depends_on_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_dependencies_depends_on_id ON service_dependencies(depends_on_id);
+
+ALTER TABLE service_states ADD COLUMN IF NOT EXISTS impacted_by INTEGER REFERENCES services(id) ON DELETE SET NULL;
@@ -0,0 +1,27 @@
+-- Services can be tagged, e.g. to scope maintenance windows
+ALTER TABLE services ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- Maintenance windows suppress alerting for the services or tags in scope.
+-- One-off windows run from starts_at to ends_at; recurring windows open each
+-- time schedule fires and last duration minutes, optionally bounded by
+-- starts_at and ends_at.
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    schedule VARCHAR(255) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    duration INTEGER NOT NULL DEFAULT 0,
+    service_ids INTEGER[] NOT NULL DEFAULT '{}',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Checks recorded during maintenance, so uptime can exclude them
+ALTER TABLE health_checks ADD COLUMN IF NOT EXISTS in_maintenance BOOLEAN NOT NULL DEFAULT false;
+
+-- Set when a service went down during maintenance and its alert is still
+-- owed once the window closes
+ALTER TABLE service_states ADD COLUMN IF NOT EXISTS alert_pending BOOLEAN NOT NULL DEFAULT false;
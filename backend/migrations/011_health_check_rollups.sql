@@ -0,0 +1,45 @@
+-- Hourly and daily aggregates of health checks, kept after raw checks are
+-- deleted. Latency columns cover successful (up or degraded) checks.
+CREATE TABLE IF NOT EXISTS health_check_rollups_hourly (
+    service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    in_maintenance BOOLEAN NOT NULL,
+    count INTEGER NOT NULL,
+    up INTEGER NOT NULL,
+    degraded INTEGER NOT NULL,
+    down INTEGER NOT NULL,
+    unknown INTEGER NOT NULL,
+    response_time_sum BIGINT NOT NULL,
+    p50 DOUBLE PRECISION,
+    p95 DOUBLE PRECISION,
+    p99 DOUBLE PRECISION,
+    PRIMARY KEY (service_id, bucket, in_maintenance)
+);
+
+CREATE TABLE IF NOT EXISTS health_check_rollups_daily (
+    service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    in_maintenance BOOLEAN NOT NULL,
+    count INTEGER NOT NULL,
+    up INTEGER NOT NULL,
+    degraded INTEGER NOT NULL,
+    down INTEGER NOT NULL,
+    unknown INTEGER NOT NULL,
+    response_time_sum BIGINT NOT NULL,
+    p50 DOUBLE PRECISION,
+    p95 DOUBLE PRECISION,
+    p99 DOUBLE PRECISION,
+    PRIMARY KEY (service_id, bucket, in_maintenance)
+);
+
+-- Tracks how far checks have been rolled up and from when raw checks and
+-- hourly rollups are still kept, so readers know which table to use
+CREATE TABLE IF NOT EXISTS health_check_rollup_state (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    hourly_rolled_to TIMESTAMP WITH TIME ZONE,
+    daily_rolled_to TIMESTAMP WITH TIME ZONE,
+    raw_from TIMESTAMP WITH TIME ZONE,
+    hourly_from TIMESTAMP WITH TIME ZONE
+);
+
+INSERT INTO health_check_rollup_state (id) VALUES (true) ON CONFLICT DO NOTHING;
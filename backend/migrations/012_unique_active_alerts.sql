@@ -0,0 +1,14 @@
+-- A service has at most one unresolved alert per severity, so concurrent
+-- checks cannot raise and escalate the same outage twice. Older duplicates
+-- are resolved first.
+UPDATE alerts a
+SET status = 'resolved', resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE a.resolved_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM alerts b
+    WHERE b.service_id = a.service_id AND b.severity = a.severity
+      AND b.resolved_at IS NULL AND b.id > a.id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_unresolved_service_id_severity
+    ON alerts(service_id, severity) WHERE resolved_at IS NULL;